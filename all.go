@@ -5,6 +5,18 @@ import (
 	_ "github.com/azraeljack/crypto-monitor/collector/binance"
 
 	// notifiers
+	_ "github.com/azraeljack/crypto-monitor/notifier/bark"
+	_ "github.com/azraeljack/crypto-monitor/notifier/dingtalk"
+	_ "github.com/azraeljack/crypto-monitor/notifier/discord"
+	_ "github.com/azraeljack/crypto-monitor/notifier/email"
+	_ "github.com/azraeljack/crypto-monitor/notifier/feishu"
+	_ "github.com/azraeljack/crypto-monitor/notifier/gotify"
+	_ "github.com/azraeljack/crypto-monitor/notifier/irc"
+	_ "github.com/azraeljack/crypto-monitor/notifier/matrix"
+	_ "github.com/azraeljack/crypto-monitor/notifier/ntfy"
+	_ "github.com/azraeljack/crypto-monitor/notifier/pushover"
+	_ "github.com/azraeljack/crypto-monitor/notifier/slack"
+	_ "github.com/azraeljack/crypto-monitor/notifier/webhook"
 	_ "github.com/azraeljack/crypto-monitor/notifier/wechat"
 
 	// strategies
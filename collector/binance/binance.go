@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"github.com/adshao/go-binance/v2"
 	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/metrics"
+	"github.com/azraeljack/crypto-monitor/status"
 	log "github.com/sirupsen/logrus"
 	"strconv"
 	"strings"
@@ -15,7 +18,8 @@ import (
 type Collector struct {
 	config *Config
 
-	ctx context.Context
+	ctx     context.Context
+	tracker *status.Tracker
 
 	client   *binance.Client
 	timeout  time.Duration
@@ -23,10 +27,16 @@ type Collector struct {
 }
 
 func (c *Collector) TestConnection() bool {
-	return c.client.NewPingService().Do(c.getContext()) == nil
+	ctx, cancel := c.getContext(c.ctx)
+	defer cancel()
+
+	start := time.Now()
+	err := c.client.NewPingService().Do(ctx)
+	metrics.ObserveRequest(c.tracker.Name(), "ping", start, err)
+	return err == nil
 }
 
-func (c *Collector) CollectWindowPrice(symbol1, symbol2 string, window time.Duration) <-chan *collector.WindowPrice {
+func (c *Collector) CollectWindowPrice(ctx context.Context, symbol1, symbol2 string, window time.Duration) <-chan *collector.WindowPrice {
 	resultCh := make(chan *collector.WindowPrice, 20)
 
 	go func() {
@@ -36,18 +46,32 @@ func (c *Collector) CollectWindowPrice(symbol1, symbol2 string, window time.Dura
 
 		for {
 			select {
+			case <-ctx.Done():
+				close(resultCh)
+				log.Info("binance collector exited")
+				return
 			case <-c.ctx.Done():
 				close(resultCh)
 				log.Info("binance collector exited")
 				return
 			case <-ticker.C:
 				log.Infof("sending new window price request of [%s - %s] to binance...", symbol1, symbol2)
-				res, err := c.client.NewListSymbolTickerService().Symbol(pair).WindowSize(fmt.Sprintf("%vm", uint64(window.Minutes()))).Do(c.getContext())
+				reqCtx, cancel := c.getContext(ctx)
+				start := time.Now()
+				res, err := c.client.NewListSymbolTickerService().Symbol(pair).WindowSize(fmt.Sprintf("%vm", uint64(window.Minutes()))).Do(reqCtx)
+				cancel()
+				if ctx.Err() != nil {
+					// stopped while waiting for the response
+					continue
+				}
+				metrics.ObserveRequest(c.tracker.Name(), "ticker", start, err)
 				if err != nil {
 					log.Errorf("failed to fetch average price_change of [%s-%s], err: %v", symbol1, symbol2, err)
+					c.tracker.Error(err)
 					continue
 				} else if len(res) < 1 {
 					log.Warnf("failed to fetch average price_change of %s-%s, result empty", symbol1, symbol2)
+					c.tracker.Error(fmt.Errorf("empty window price of %s", pair))
 					continue
 				}
 				price := res[0]
@@ -67,13 +91,18 @@ func (c *Collector) CollectWindowPrice(symbol1, symbol2 string, window time.Dura
 					OpenTime:            uint64(price.OpenTime),
 					CloseTime:           uint64(price.CloseTime),
 					OrderCount:          uint64(price.Count),
+					Exchange:            c.Type(),
 				}
 
+				c.tracker.Record("prices")
+				metrics.PriceReceived(c.tracker.Name(), windowPrice.SymbolPair())
 				select {
 				case resultCh <- windowPrice:
 					log.Debugf("fetched new window price_change of [%s-%s]: %s", symbol1, symbol2, windowPrice.String())
 				default:
 					log.Warnf("result channel full of [%s-%s], discard data: %s", symbol1, symbol2, windowPrice.String())
+					c.tracker.Count("dropped")
+					metrics.PriceDropped(c.tracker.Name(), windowPrice.SymbolPair())
 				}
 			}
 		}
@@ -82,7 +111,7 @@ func (c *Collector) CollectWindowPrice(symbol1, symbol2 string, window time.Dura
 	return resultCh
 }
 
-func (c *Collector) CollectAvgPrice(symbol1, symbol2 string) <-chan float64 {
+func (c *Collector) CollectAvgPrice(ctx context.Context, symbol1, symbol2 string) <-chan float64 {
 	resultCh := make(chan float64, 20)
 
 	go func() {
@@ -92,13 +121,26 @@ func (c *Collector) CollectAvgPrice(symbol1, symbol2 string) <-chan float64 {
 
 		for {
 			select {
+			case <-ctx.Done():
+				close(resultCh)
+				log.Info("collector exited")
+				return
 			case <-c.ctx.Done():
 				close(resultCh)
 				log.Info("collector exited")
+				return
 			case <-ticker.C:
-				res, err := c.client.NewAveragePriceService().Symbol(pair).Do(c.getContext())
+				reqCtx, cancel := c.getContext(ctx)
+				start := time.Now()
+				res, err := c.client.NewAveragePriceService().Symbol(pair).Do(reqCtx)
+				cancel()
+				if ctx.Err() != nil {
+					continue
+				}
+				metrics.ObserveRequest(c.tracker.Name(), "avgPrice", start, err)
 				if err != nil {
 					log.Errorf("failed to fetch average price_change of %s-%s, err: %v", symbol1, symbol2, err)
+					c.tracker.Error(err)
 					continue
 				}
 
@@ -107,11 +149,15 @@ func (c *Collector) CollectAvgPrice(symbol1, symbol2 string) <-chan float64 {
 					continue
 				}
 
+				c.tracker.Record("prices")
+				metrics.PriceReceived(c.tracker.Name(), symbol1+"-"+symbol2)
 				select {
 				case resultCh <- price:
 					log.Debugf("fetched new price_change of %s-%s: %v", symbol1, symbol2, price)
 				default:
 					log.Warnf("result channel full of %s-%s", symbol1, symbol2)
+					c.tracker.Count("dropped")
+					metrics.PriceDropped(c.tracker.Name(), symbol1+"-"+symbol2)
 				}
 			}
 		}
@@ -124,9 +170,8 @@ func (c *Collector) Type() string {
 	return "binance"
 }
 
-func (c *Collector) getContext() context.Context {
-	ctx, _ := context.WithTimeout(c.ctx, c.timeout)
-	return ctx
+func (c *Collector) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.timeout)
 }
 
 func combineSymbols(symbols ...string) string {
@@ -152,20 +197,20 @@ func toJSONString(data any) string {
 	return string(res)
 }
 
-func NewBinanceCollector(ctx context.Context, rawConf json.RawMessage) collector.Collector {
+func NewBinanceCollector(ctx context.Context, rawConf json.RawMessage) (collector.Collector, error) {
 	conf := &Config{}
-	if err := json.Unmarshal(rawConf, conf); err != nil {
-		log.Panic("failed to parse binance collector config", err)
+	if err := config.Decode(rawConf, conf); err != nil {
+		return nil, err
 	}
 
-	timeout, err := time.ParseDuration(conf.Timeout)
-	if err != nil {
-		timeout = 5 * time.Second
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	interval := config.ParseDuration(&errs, "interval", conf.Interval, 5*time.Second)
+	if interval == 0 {
+		errs.Addf("interval", "must be positive")
 	}
-
-	interval, err := time.ParseDuration(conf.Interval)
-	if err != nil {
-		interval = 5 * time.Second
+	if err := errs.Err(); err != nil {
+		return nil, err
 	}
 
 	var client *binance.Client
@@ -181,5 +226,6 @@ func NewBinanceCollector(ctx context.Context, rawConf json.RawMessage) collector
 		timeout:  timeout,
 		interval: interval,
 		ctx:      ctx,
-	}
+		tracker:  status.FromContext(ctx),
+	}, nil
 }
@@ -4,16 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	log "github.com/sirupsen/logrus"
+	"github.com/azraeljack/crypto-monitor/config"
 	"sync"
 	"time"
 )
 
-type Builder func(ctx context.Context, rawConf json.RawMessage) Collector
+type Builder func(ctx context.Context, rawConf json.RawMessage) (Collector, error)
 
 type Collector interface {
-	CollectAvgPrice(symbol1, symbol2 string) <-chan float64
-	CollectWindowPrice(symbol1, symbol2 string, window time.Duration) <-chan *WindowPrice
+	// CollectAvgPrice and CollectWindowPrice poll prices until ctx or the collector's own context is done.
+	CollectAvgPrice(ctx context.Context, symbol1, symbol2 string) <-chan float64
+	CollectWindowPrice(ctx context.Context, symbol1, symbol2 string, window time.Duration) <-chan *WindowPrice
 	Type() string
 	TestConnection() bool
 }
@@ -32,6 +33,9 @@ type WindowPrice struct {
 	OrderCount          uint64  `json:"order_count"`
 	AbsolutePriceChange float64 `json:"absolute_price_change"`
 	RelativePriceChange float64 `json:"relative_price_change"`
+
+	// Exchange is where the price comes from, the type of its collector
+	Exchange string `json:"exchange,omitempty"`
 }
 
 func (w WindowPrice) String() string {
@@ -43,6 +47,11 @@ func (w WindowPrice) SymbolPair() string {
 	return fmt.Sprintf("%s-%s", w.Symbol1, w.Symbol2)
 }
 
+// Rising reports whether the price went up within the window.
+func (w WindowPrice) Rising() bool {
+	return w.AbsolutePriceChange >= 0
+}
+
 var registry Registry
 
 type Registry struct {
@@ -53,24 +62,30 @@ func (c *Registry) Register(tpy string, builder Builder) {
 	c.collectors.Store(tpy, builder)
 }
 
-func (c *Registry) GetCollector(ctx context.Context, rawConf json.RawMessage) Collector {
-	type collectorTypeConf struct {
-		Type string `json:"type"`
-	}
-
-	typeConf := &collectorTypeConf{}
+func (c *Registry) GetCollector(ctx context.Context, rawConf json.RawMessage) (Collector, error) {
+	typeConf := &config.Component{}
 	if err := json.Unmarshal(rawConf, typeConf); err != nil {
-		log.Panic("load config fail", err)
+		return nil, config.Errors{{Err: err}}
 	}
 
 	builder, exist := c.collectors.Load(typeConf.Type)
 	if !exist {
-		return nil
+		return nil, config.Errors{{Path: "type", Err: config.UnknownType(typeConf.Type, c.Types())}}
 	}
 
 	return builder.(Builder)(ctx, rawConf)
 }
 
+// Types lists the registered collector types.
+func (c *Registry) Types() []string {
+	var types []string
+	c.collectors.Range(func(key, _ any) bool {
+		types = append(types, key.(string))
+		return true
+	})
+	return types
+}
+
 func GetRegistry() *Registry {
 	return &registry
 }
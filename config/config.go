@@ -8,4 +8,72 @@ type Config struct {
 	Notifiers  []json.RawMessage `json:"notifiers"`
 	Collectors []json.RawMessage `json:"collectors"`
 	Strategies []json.RawMessage `json:"strategies"`
+
+	Routing *Routing `json:"routing"`
+	Server  *Server  `json:"server"`
+	Storage *Storage `json:"storage"`
+	Journal *Journal `json:"journal"`
+	State   *State   `json:"state"`
+
+	Escalation *Escalation `json:"escalation"`
+
+	// Locale is the language of the monitor's own messages and the default one of message
+	// templates, "zh" or "en", "zh" by default.
+	Locale string `json:"locale"`
+
+	// Include lists further config files, directories or glob patterns, relative to the
+	// including file, whose components are appended to this config.
+	Include []string `json:"include"`
+
+	// Files are the files and directories the config was loaded from.
+	Files []string `json:"-"`
+
+	// origins records the file each component and the routing came from, keyed by field.
+	origins map[string][]origin
+}
+
+type origin struct {
+	file  string
+	index int
+}
+
+// Path returns the path of the i-th component of a list field, or of the field itself if i
+// is negative, prefixed by its file if it was loaded from another file than the main one.
+func (c *Config) Path(field string, i int) string {
+	path := field
+	if i >= 0 {
+		path = Index(field, i)
+	}
+
+	origins := c.origins[field]
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(origins) || len(origins[i].file) == 0 {
+		return path
+	}
+
+	if origins[i].index >= 0 {
+		path = Index(field, origins[i].index)
+	}
+	return origins[i].file + ": " + path
+}
+
+// Component holds the fields shared by every collector, notifier and strategy config.
+type Component struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	// Collectors and Notifiers bind a strategy to the named components. A strategy
+	// uses every collector if none is listed, and every notifier, or the routing
+	// rules if configured, if no notifier is listed.
+	Collectors []string `json:"collectors"`
+	Notifiers  []string `json:"notifiers"`
+
+	// QuietHours silences a notifier every day during the time range.
+	QuietHours *QuietHours `json:"quiet_hours"`
+	// Grouping batches the alerts a notifier receives within a short time into one message.
+	Grouping *Group `json:"grouping"`
+	// MessageTemplate renders the alerts of a strategy, or re-renders those a notifier sends.
+	MessageTemplate *MessageTemplate `json:"message_template"`
 }
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// componentFields are decoded by the monitor and ignored when decoding a component's own config,
+// notifierFields and strategyFields only for notifiers and strategies.
+var (
+	componentFields = []string{"type", "name", "collectors", "notifiers"}
+	notifierFields  = []string{"quiet_hours", "grouping", "message_template"}
+	strategyFields  = []string{"message_template"}
+)
+
+// Decode strictly decodes a collector config into v, rejecting unknown fields.
+// The returned error is an Errors carrying the JSON path of the offending field.
+func Decode(rawConf json.RawMessage, v any) error {
+	return decode(rawConf, v, componentFields)
+}
+
+// DecodeNotifier strictly decodes a notifier config into v like Decode.
+func DecodeNotifier(rawConf json.RawMessage, v any) error {
+	return decode(rawConf, v, componentFields, notifierFields)
+}
+
+// DecodeStrategy strictly decodes a strategy config into v like Decode.
+func DecodeStrategy(rawConf json.RawMessage, v any) error {
+	return decode(rawConf, v, componentFields, strategyFields)
+}
+
+func decode(rawConf json.RawMessage, v any, ignored ...[]string) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(rawConf, &fields); err != nil {
+		return Errors{{Err: err}}
+	}
+	for _, names := range ignored {
+		for _, name := range names {
+			delete(fields, name)
+		}
+	}
+	stripped, _ := json.Marshal(fields)
+
+	return DecodeStrict(stripped, v)
+}
+
+// DecodeStrict decodes data into v, rejecting unknown fields.
+func DecodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return Errors{decodeError(data, err)}
+	}
+	return nil
+}
+
+func decodeError(data []byte, err error) *FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &FieldError{Path: typeErr.Field, Err: fmt.Errorf("expecting %v, got %s", typeErr.Type, typeErr.Value)}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+		return &FieldError{Err: fmt.Errorf("line %d: %v", line, syntaxErr)}
+	}
+
+	// encoding/json has no typed error for unknown fields
+	if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+		return &FieldError{Path: strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), `"`), Err: errors.New("unknown field")}
+	}
+
+	return &FieldError{Err: err}
+}
+
+// ParseDuration parses an optional duration field, an empty value yields def
+// while an invalid one is recorded in errs.
+func ParseDuration(errs *Errors, field, value string, def time.Duration) time.Duration {
+	if len(value) == 0 {
+		return def
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		errs.Addf(field, "invalid duration %q", value)
+		return def
+	}
+	if duration < 0 {
+		errs.Addf(field, "duration must not be negative")
+		return def
+	}
+	return duration
+}
+
+// Require records an error in errs if a required string field is empty.
+func Require(errs *Errors, field, value string) {
+	if len(strings.TrimSpace(value)) == 0 {
+		errs.Addf(field, "required")
+	}
+}
+
+// OneOf records an error in errs if value isn't one of the allowed values.
+func OneOf(errs *Errors, field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	errs.Addf(field, "invalid value %q, expecting one of %s", value, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeIgnoresComponentFieldsByKind(t *testing.T) {
+	type own struct {
+		URL string `json:"url"`
+	}
+	tests := []struct {
+		name   string
+		decode func(json.RawMessage, any) error
+		field  string
+		valid  bool
+	}{
+		{"collector quiet hours", Decode, `"quiet_hours": {"start": "22:00", "end": "07:00"}`, false},
+		{"collector grouping", Decode, `"grouping": {}`, false},
+		{"collector message template", Decode, `"message_template": {}`, false},
+		{"notifier quiet hours", DecodeNotifier, `"quiet_hours": {"start": "22:00", "end": "07:00"}`, true},
+		{"notifier grouping", DecodeNotifier, `"grouping": {}`, true},
+		{"notifier message template", DecodeNotifier, `"message_template": {}`, true},
+		{"strategy quiet hours", DecodeStrategy, `"quiet_hours": {"start": "22:00", "end": "07:00"}`, false},
+		{"strategy grouping", DecodeStrategy, `"grouping": {}`, false},
+		{"strategy message template", DecodeStrategy, `"message_template": {}`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rawConf := json.RawMessage(`{"type": "test", "name": "test", "url": "http://localhost", ` + test.field + `}`)
+			conf := &own{}
+			err := test.decode(rawConf, conf)
+			if test.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !test.valid && err == nil {
+				t.Error("accepted a field the component doesn't have")
+			}
+			if test.valid && conf.URL != "http://localhost" {
+				t.Errorf("decoded url %q", conf.URL)
+			}
+		})
+	}
+}
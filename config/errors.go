@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with the config value at a JSON path, e.g. strategies[0].window_size.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects every problem found while loading a config, so they can be reported together.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Err returns nil if no error was collected.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Add records err at path, errors already carrying paths are nested below it.
+func (e *Errors) Add(path string, err error) {
+	if err == nil {
+		return
+	}
+
+	var nested Errors
+	if errors.As(err, &nested) {
+		for _, fieldErr := range nested {
+			*e = append(*e, &FieldError{Path: JoinPath(path, fieldErr.Path), Err: fieldErr.Err})
+		}
+		return
+	}
+
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		*e = append(*e, &FieldError{Path: JoinPath(path, fieldErr.Path), Err: fieldErr.Err})
+		return
+	}
+
+	*e = append(*e, &FieldError{Path: path, Err: err})
+}
+
+func (e *Errors) Addf(path, format string, args ...any) {
+	e.Add(path, fmt.Errorf(format, args...))
+}
+
+// JoinPath appends a field or index to a JSON path.
+func JoinPath(parent, child string) string {
+	switch {
+	case len(parent) == 0:
+		return child
+	case len(child) == 0:
+		return parent
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	default:
+		return parent + "." + child
+	}
+}
+
+// Index returns the JSON path of the i-th element of a list field.
+func Index(field string, i int) string {
+	return fmt.Sprintf("%s[%d]", field, i)
+}
@@ -0,0 +1,27 @@
+package config
+
+// Escalation configures escalation policies. Strategies and routes name a policy like a
+// notifier, it notifies its steps in turn until the alert is acknowledged through the link
+// appended to the message.
+type Escalation struct {
+	// AckURL is the base URL of the status server as reachable by the recipients, used in
+	// acknowledgement links, http://<server.listen> by default if it listens on a specific,
+	// non-loopback address.
+	AckURL   string              `json:"ack_url"`
+	Policies []*EscalationPolicy `json:"policies"`
+}
+
+type EscalationPolicy struct {
+	Name string `json:"name"`
+	// Severities lists the severities escalated, alerts of other severities only go to the
+	// first step. Every severity is escalated if empty.
+	Severities []string          `json:"severities"`
+	Steps      []*EscalationStep `json:"steps"`
+}
+
+type EscalationStep struct {
+	Notifiers []string `json:"notifiers"`
+	// After is how long the previous step is left unacknowledged before notifying this one,
+	// it is required by every step but the first.
+	After string `json:"after"`
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"strings"
+)
+
+// formats maps the supported config file extensions to their parsers.
+var formats = map[string]func(data []byte) (any, error){
+	".json": parseJSON,
+	".yaml": parseYAML,
+	".yml":  parseYAML,
+	".toml": parseTOML,
+}
+
+func isConfigFile(file string) bool {
+	_, ok := formats[strings.ToLower(filepath.Ext(file))]
+	return ok
+}
+
+// parse decodes a config file into a generic tree by its extension, files
+// without a known extension are parsed as JSON.
+func parse(file string, data []byte) (any, error) {
+	parser, ok := formats[strings.ToLower(filepath.Ext(file))]
+	if !ok {
+		parser = parseJSON
+	}
+	return parser(data)
+}
+
+func parseJSON(data []byte) (any, error) {
+	var tree any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, decodeError(data, err)
+	}
+	return tree, nil
+}
+
+func parseYAML(data []byte) (any, error) {
+	var tree any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func parseTOML(data []byte) (any, error) {
+	tree := make(map[string]any)
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("line %d: %s", parseErr.Position.Line, parseErr.Message)
+		}
+		return nil, err
+	}
+	return tree, nil
+}
@@ -0,0 +1,14 @@
+package config
+
+// Group batches alerts arriving within a short time, e.g. when the whole market moves, into
+// one message per group listing every pair by the size of its move.
+type Group struct {
+	// By lists what alerts are grouped by, among strategy, direction, exchange, severity and
+	// state. Strategy, direction and exchange by default, resolved alerts are never grouped
+	// with firing ones.
+	By []string `json:"by"`
+	// Wait is how long a group collects alerts after the first one before it is sent, 30s by default.
+	Wait string `json:"wait"`
+	// MaxSize sends a group as soon as it has as many alerts, 50 by default.
+	MaxSize int `json:"max_size"`
+}
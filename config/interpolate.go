@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// placeholder matches ${ENV_VAR} and ${file:/path/to/secret}, $${...} escapes a literal ${...}.
+var placeholder = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// interpolate replaces the placeholders in every string value of tree, keys are left untouched.
+func interpolate(errs *Errors, path string, tree any) any {
+	switch value := tree.(type) {
+	case string:
+		expanded, err := expand(value)
+		if err != nil {
+			errs.Add(path, err)
+		}
+		return expanded
+	case map[string]any:
+		for key, child := range value {
+			value[key] = interpolate(errs, JoinPath(path, key), child)
+		}
+		return value
+	case []any:
+		for i, child := range value {
+			value[i] = interpolate(errs, fmt.Sprintf("%s[%d]", path, i), child)
+		}
+		return value
+	case []map[string]any:
+		// arrays of tables in TOML
+		list := make([]any, 0, len(value))
+		for i, child := range value {
+			list = append(list, interpolate(errs, fmt.Sprintf("%s[%d]", path, i), child))
+		}
+		return list
+	default:
+		return value
+	}
+}
+
+func expand(value string) (string, error) {
+	var err error
+	expanded := placeholder.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		resolved, resolveErr := resolve(match[2 : len(match)-1])
+		if resolveErr != nil && err == nil {
+			err = resolveErr
+		}
+		return resolved
+	})
+	return expanded, err
+}
+
+func resolve(ref string) (string, error) {
+	if strings.HasPrefix(ref, "file:") {
+		file := strings.TrimPrefix(ref, "file:")
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
@@ -0,0 +1,10 @@
+package config
+
+// Journal configures recording every alert fired by strategies, it is disabled if not configured.
+type Journal struct {
+	// Path is the file alerts are appended to as JSON lines.
+	Path string `json:"path"`
+	// Retention is how long alerts are kept, forever if empty. Older alerts are pruned when
+	// the journal is opened and hourly afterwards.
+	Retention string `json:"retention"`
+}
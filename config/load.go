@@ -0,0 +1,246 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Load reads the config file, or every config file of a directory, along with the files they
+// include. JSON, YAML and TOML files are supported by extension, and ${ENV_VAR} or
+// ${file:/path} placeholders in string values are replaced. Component configs are decoded
+// later by their builders.
+// The config is returned along with the error if it could be loaded despite problems like unknown fields.
+func Load(configFile string) (*Config, error) {
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	l := &loader{
+		conf:    &Config{origins: make(map[string][]origin)},
+		baseDir: configFile,
+		loaded:  make(map[string]bool),
+	}
+	if info.IsDir() {
+		l.loadDir(configFile)
+	} else {
+		l.baseDir = filepath.Dir(configFile)
+		l.main = configFile
+		l.loadFile(configFile)
+	}
+
+	return l.conf, l.errs.Err()
+}
+
+type loader struct {
+	conf *Config
+	errs Errors
+
+	// baseDir is where file names in errors are relative to, errors of main carry no file name
+	baseDir string
+	main    string
+
+	loaded map[string]bool
+}
+
+func (l *loader) loadDir(dir string) {
+	l.conf.Files = append(l.conf.Files, dir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		l.errs.Add(l.label(dir), err)
+		return
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !isConfigFile(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		l.loadFile(file)
+	}
+}
+
+func (l *loader) loadFile(file string) {
+	if l.loaded[file] {
+		return
+	}
+	l.loaded[file] = true
+	l.conf.Files = append(l.conf.Files, file)
+
+	label := l.label(file)
+	errs := Errors{}
+	defer func() {
+		for _, err := range errs {
+			if len(label) > 0 {
+				err = &FieldError{Path: strings.TrimSuffix(label+": "+err.Path, ": "), Err: err.Err}
+			}
+			l.errs = append(l.errs, err)
+		}
+	}()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		errs.Add("", fmt.Errorf("failed to read config: %w", err))
+		return
+	}
+
+	tree, err := parse(file, data)
+	if err != nil {
+		errs.Add("", err)
+		return
+	}
+	if tree == nil {
+		// an empty file
+		return
+	}
+	tree = interpolate(&errs, "", tree)
+
+	data, err = json.Marshal(tree)
+	if err != nil {
+		errs.Add("", err)
+		return
+	}
+
+	conf := &Config{}
+	if err := DecodeStrict(data, conf); err != nil {
+		errs.Add("", err)
+		if json.Unmarshal(data, conf) != nil {
+			return
+		}
+	}
+
+	l.templateFiles(filepath.Dir(file), conf.Notifiers)
+	l.templateFiles(filepath.Dir(file), conf.Strategies)
+	l.merge(&errs, label, conf)
+
+	for i, include := range conf.Include {
+		if err := l.include(filepath.Dir(file), include); err != nil {
+			errs.Add(Index("include", i), err)
+		}
+	}
+}
+
+func (l *loader) merge(errs *Errors, label string, conf *Config) {
+	appendComponents := func(field string, to *[]json.RawMessage, from []json.RawMessage) {
+		for i, component := range from {
+			*to = append(*to, component)
+			l.conf.origins[field] = append(l.conf.origins[field], origin{file: label, index: i})
+		}
+	}
+	appendComponents("collectors", &l.conf.Collectors, conf.Collectors)
+	appendComponents("notifiers", &l.conf.Notifiers, conf.Notifiers)
+	appendComponents("strategies", &l.conf.Strategies, conf.Strategies)
+
+	// sections which can't be merged must be defined once
+	setOnce := func(field string, defined, exist bool, set func()) {
+		if !defined {
+			return
+		}
+		if exist {
+			errs.Add(field, fmt.Errorf("%s is already defined in another file", field))
+			return
+		}
+		set()
+		l.conf.origins[field] = []origin{{file: label, index: -1}}
+	}
+	setOnce("locale", len(conf.Locale) > 0, len(l.conf.Locale) > 0, func() { l.conf.Locale = conf.Locale })
+	setOnce("routing", conf.Routing != nil, l.conf.Routing != nil, func() { l.conf.Routing = conf.Routing })
+	setOnce("server", conf.Server != nil, l.conf.Server != nil, func() { l.conf.Server = conf.Server })
+	setOnce("storage", conf.Storage != nil, l.conf.Storage != nil, func() { l.conf.Storage = conf.Storage })
+	setOnce("journal", conf.Journal != nil, l.conf.Journal != nil, func() { l.conf.Journal = conf.Journal })
+	setOnce("state", conf.State != nil, l.conf.State != nil, func() { l.conf.State = conf.State })
+	setOnce("escalation", conf.Escalation != nil, l.conf.Escalation != nil, func() { l.conf.Escalation = conf.Escalation })
+}
+
+// templateFiles resolves the template files of the components relative to dir, like includes,
+// and watches them so changing a template reloads the config.
+func (l *loader) templateFiles(dir string, components []json.RawMessage) {
+	for i, component := range components {
+		decoder := json.NewDecoder(bytes.NewReader(component))
+		decoder.UseNumber()
+		var fields map[string]any
+		if decoder.Decode(&fields) != nil {
+			continue
+		}
+		tmpl, ok := fields["message_template"].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		changed := false
+		for _, field := range []string{"firing_file", "resolved_file"} {
+			path, ok := tmpl[field].(string)
+			if !ok || len(path) == 0 {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+				tmpl[field] = path
+				changed = true
+			}
+			l.conf.Files = append(l.conf.Files, path)
+		}
+		if !changed {
+			continue
+		}
+		if data, err := json.Marshal(fields); err == nil {
+			components[i] = data
+		}
+	}
+}
+
+// include loads a file, directory or glob pattern relative to dir.
+func (l *loader) include(dir, pattern string) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	matches := []string{pattern}
+	if strings.ContainsAny(pattern, "*?[") {
+		var err error
+		if matches, err = filepath.Glob(pattern); err != nil {
+			return err
+		}
+		// watch the directory for files matching the pattern later
+		l.conf.Files = append(l.conf.Files, filepath.Dir(pattern))
+	}
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			l.loadDir(match)
+		} else {
+			l.loadFile(match)
+		}
+	}
+	return nil
+}
+
+func (l *loader) label(file string) string {
+	if file == l.main {
+		return ""
+	}
+	if rel, err := filepath.Rel(l.baseDir, file); err == nil {
+		return rel
+	}
+	return file
+}
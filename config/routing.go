@@ -0,0 +1,28 @@
+package config
+
+// Routing decides which notifiers receive the alerts of strategies, routes are
+// evaluated in order and the first match wins unless it sets Continue.
+type Routing struct {
+	// Default lists the notifiers used when no route matches, all notifiers in config order if empty.
+	Default []string `json:"default"`
+	Routes  []*Route `json:"routes"`
+}
+
+// Route matches alerts on the given criteria, an empty criterion matches anything.
+type Route struct {
+	Strategies []string   `json:"strategies"`
+	Pairs      []string   `json:"pairs"`
+	Severities []string   `json:"severities"`
+	Time       *TimeRange `json:"time"`
+
+	Notifiers []string `json:"notifiers"`
+	// Continue keeps evaluating the following routes after this one matched.
+	Continue bool `json:"continue"`
+}
+
+// TimeRange is a daily time window like 22:00 - 08:00, which may wrap around midnight.
+type TimeRange struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone"`
+}
@@ -0,0 +1,15 @@
+package config
+
+// Server configures the embedded HTTP server exposing the health, status and metrics of the monitor,
+// it is disabled if not configured.
+type Server struct {
+	Listen string `json:"listen"`
+
+	// ReadyMaxAge is how recent the last price of every collector in use must be for the
+	// monitor to be ready, 5m by default.
+	ReadyMaxAge string `json:"ready_max_age"`
+	// CheckInterval is how often the connection of every collector is tested, 1m by default.
+	CheckInterval string `json:"check_interval"`
+	// Token, if set, must be sent as a bearer token to add or end silences.
+	Token string `json:"token"`
+}
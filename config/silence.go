@@ -0,0 +1,10 @@
+package config
+
+// QuietHours is a daily time range, e.g. from "22:00" to "07:00", during which a notifier
+// holds its messages back and sends a summary of them once the range ends.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Timezone is an IANA time zone name, e.g. "Asia/Shanghai", the local one by default.
+	Timezone string `json:"timezone"`
+}
@@ -0,0 +1,10 @@
+package config
+
+// State configures persisting the dedupe and throttle windows of strategies and notifiers,
+// so alerts already sent aren't sent again after a restart. They are kept in memory only if
+// not configured.
+type State struct {
+	// Backend is either "file", a JSON file rewritten on every change, or "bbolt", "file" by default.
+	Backend string `json:"backend"`
+	Path    string `json:"path"`
+}
@@ -0,0 +1,19 @@
+package config
+
+// Storage configures recording the prices fetched by collectors for strategies to look back at,
+// it is disabled if not configured.
+type Storage struct {
+	Path string `json:"path"`
+
+	// Retention is how long prices are kept, forever if empty.
+	Retention string `json:"retention"`
+	// Downsample merges the prices older than After into klines of Resolution.
+	Downsample []*Downsample `json:"downsample"`
+	// CompactInterval is how often retention and downsampling are applied, 10m by default.
+	CompactInterval string `json:"compact_interval"`
+}
+
+type Downsample struct {
+	After      string `json:"after"`
+	Resolution string `json:"resolution"`
+}
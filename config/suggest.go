@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// UnknownType builds the error for an unregistered component type, suggesting
+// the closest registered one.
+func UnknownType(name string, registered []string) error {
+	sort.Strings(registered)
+
+	best, bestDistance := "", len(name)/2+2
+	for _, candidate := range registered {
+		if distance := levenshtein(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	if len(name) == 0 {
+		return fmt.Errorf("required, expecting one of %v", registered)
+	}
+	if len(best) > 0 {
+		return fmt.Errorf("unknown type %q, did you mean %q?", name, best)
+	}
+	return fmt.Errorf("unknown type %q, expecting one of %v", name, registered)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minOf(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func minOf(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
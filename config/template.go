@@ -0,0 +1,14 @@
+package config
+
+// MessageTemplate customizes the message of an alert, the default one of the locale is used
+// for a template left empty.
+type MessageTemplate struct {
+	// Locale picks the default templates, "zh" or "en", "zh" by default.
+	Locale string `json:"locale"`
+	// Firing and Resolved are text/template sources for alerts firing and resolved,
+	// FiringFile and ResolvedFile the path of a file holding one instead.
+	Firing       string `json:"firing"`
+	FiringFile   string `json:"firing_file"`
+	Resolved     string `json:"resolved"`
+	ResolvedFile string `json:"resolved_file"`
+}
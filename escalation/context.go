@@ -0,0 +1,18 @@
+package escalation
+
+import "context"
+
+type schedulerKey struct{}
+
+// NewContext returns a context carrying the scheduler for the policies built with it.
+func NewContext(ctx context.Context, s *Scheduler) context.Context {
+	return context.WithValue(ctx, schedulerKey{}, s)
+}
+
+// FromContext returns the scheduler of ctx, or a new one if it carries none.
+func FromContext(ctx context.Context) *Scheduler {
+	if s, ok := ctx.Value(schedulerKey{}).(*Scheduler); ok {
+		return s
+	}
+	return NewScheduler()
+}
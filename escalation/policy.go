@@ -0,0 +1,171 @@
+package escalation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type step struct {
+	notifiers []notifier.Notifier
+	after     time.Duration
+}
+
+// send delivers msg to every notifier of the step, it only fails if none delivered it.
+func (s *step) send(msg, from string, throttle bool, details *notifier.Details) error {
+	var firstErr error
+	delivered := false
+	for _, n := range s.notifiers {
+		if err := notifier.Send(n, msg, from, throttle, details); err == nil {
+			delivered = true
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if delivered {
+		return nil
+	}
+	return firstErr
+}
+
+// Policy is a Notifier sending alerts to its first step, then to the following steps in
+// turn as long as nobody acknowledges them.
+type Policy struct {
+	name       string
+	severities map[notifier.Severity]struct{}
+	steps      []*step
+	ackURL     string
+	scheduler  *Scheduler
+	texts      *templates.Texts
+}
+
+// NewPolicies builds the policies of conf by name, notifiers are looked up by name.
+// Acknowledgement links point to ackURL unless conf sets its own, messages are in the locale of texts.
+func NewPolicies(conf *config.Escalation, ackURL string, notifiers map[string]notifier.Notifier, scheduler *Scheduler, texts *templates.Texts) (map[string]*Policy, error) {
+	errs := config.Errors{}
+	if len(conf.AckURL) > 0 {
+		if _, err := url.Parse(conf.AckURL); err != nil {
+			errs.Addf("ack_url", "invalid URL %q", conf.AckURL)
+		}
+		ackURL = conf.AckURL
+	}
+	if len(ackURL) == 0 {
+		errs.Addf("ack_url", "required unless the server listens on an address recipients can reach")
+	}
+
+	policies := make(map[string]*Policy, len(conf.Policies))
+	for i, policyConf := range conf.Policies {
+		path := config.Index("policies", i)
+		config.Require(&errs, config.JoinPath(path, "name"), policyConf.Name)
+		if _, exist := policies[policyConf.Name]; exist {
+			errs.Addf(config.JoinPath(path, "name"), "duplicate policy name %q", policyConf.Name)
+		} else if _, exist := notifiers[policyConf.Name]; exist {
+			errs.Addf(config.JoinPath(path, "name"), "name %q is taken by a notifier", policyConf.Name)
+		}
+
+		policy := &Policy{
+			name:       policyConf.Name,
+			severities: make(map[notifier.Severity]struct{}, len(policyConf.Severities)),
+			ackURL:     strings.TrimSuffix(ackURL, "/"),
+			scheduler:  scheduler,
+			texts:      texts,
+		}
+		for j, name := range policyConf.Severities {
+			severity, err := notifier.ParseSeverity(name)
+			if err != nil {
+				errs.Add(config.Index(config.JoinPath(path, "severities"), j), err)
+				continue
+			}
+			policy.severities[severity] = struct{}{}
+		}
+
+		if len(policyConf.Steps) == 0 {
+			errs.Addf(config.JoinPath(path, "steps"), "at least one step is required")
+		}
+		for j, stepConf := range policyConf.Steps {
+			stepPath := config.Index(config.JoinPath(path, "steps"), j)
+			s := &step{after: config.ParseDuration(&errs, config.JoinPath(stepPath, "after"), stepConf.After, 0)}
+			if j > 0 && s.after == 0 {
+				errs.Addf(config.JoinPath(stepPath, "after"), "must be positive")
+			}
+			if len(stepConf.Notifiers) == 0 {
+				errs.Addf(config.JoinPath(stepPath, "notifiers"), "at least one notifier is required")
+			}
+			for k, name := range stepConf.Notifiers {
+				n, exist := notifiers[name]
+				if !exist {
+					errs.Addf(config.Index(config.JoinPath(stepPath, "notifiers"), k), "unknown notifier %q", name)
+					continue
+				}
+				s.notifiers = append(s.notifiers, n)
+			}
+			policy.steps = append(policy.steps, s)
+		}
+
+		policies[policy.name] = policy
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (p *Policy) Notify(msg, from string, throttle bool) error {
+	return p.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+// NotifyDetails sends the alert to the first step, and schedules the next one unless the
+// alert's severity isn't escalated or the first step held it back. Notifying an incident
+// already escalating keeps its escalation and acknowledgement link, a resolved alert stops it.
+func (p *Policy) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	if details.State == notifier.StateResolved {
+		p.scheduler.resolve(p.name, incidentOf(from, details))
+	}
+	if !p.escalates(details) {
+		return p.steps[0].send(msg, from, throttle, details)
+	}
+
+	pending, copied := p.scheduler.add(p, msg, from, details)
+	err := p.steps[0].send(p.message(copied, 0), from, throttle, details)
+	if errors.Is(err, notifier.ErrThrottled) || errors.Is(err, notifier.ErrSilenced) {
+		p.scheduler.drop(pending)
+		return err
+	}
+	// failing to reach the first step is all the more reason to escalate
+	p.scheduler.schedule(pending)
+	return err
+}
+
+func (p *Policy) escalates(details *notifier.Details) bool {
+	if len(p.steps) < 2 || details.State == notifier.StateResolved {
+		return false
+	}
+	if len(p.severities) == 0 {
+		return true
+	}
+	_, exist := p.severities[details.Severity]
+	return exist
+}
+
+// notifyStep sends the pending alert to a step.
+func (p *Policy) notifyStep(pending *Pending, step int) error {
+	return p.steps[step].send(p.message(pending, step), pending.From, false, pending.details)
+}
+
+// message appends the acknowledgement link to the alert, and tells how long it went
+// unacknowledged once escalated.
+func (p *Policy) message(pending *Pending, step int) string {
+	link := fmt.Sprintf("%s/ack?id=%s", p.ackURL, pending.ID)
+	ack := fmt.Sprintf(p.texts.Acknowledge, link)
+	if step == 0 {
+		return pending.Message + "\n" + ack
+	}
+	unacked := time.Since(pending.Created).Truncate(time.Second)
+	return fmt.Sprintf(p.texts.Escalated, unacked) + "\n" + pending.Message + "\n" + ack
+}
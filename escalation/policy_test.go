@@ -0,0 +1,94 @@
+package escalation
+
+import (
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (r *recorder) Notify(msg, from string, throttle bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.messages)
+}
+
+func newPolicy(t *testing.T) (*Policy, *Scheduler, *recorder, *recorder) {
+	t.Helper()
+	first, second := &recorder{}, &recorder{}
+	scheduler := NewScheduler()
+	t.Cleanup(scheduler.Stop)
+	conf := &config.Escalation{Policies: []*config.EscalationPolicy{{
+		Name: "oncall",
+		Steps: []*config.EscalationStep{
+			{Notifiers: []string{"first"}},
+			{Notifiers: []string{"second"}, After: "50ms"},
+		},
+	}}}
+	notifiers := map[string]notifier.Notifier{"first": first, "second": second}
+	policies, err := NewPolicies(conf, "http://localhost:8080", notifiers, scheduler, templates.TextsOf(templates.LocaleEn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policies["oncall"], scheduler, first, second
+}
+
+func TestPolicyEscalatesUnacknowledgedAlerts(t *testing.T) {
+	policy, _, first, second := newPolicy(t)
+
+	details := &notifier.Details{Strategy: "btc", State: "firing", Incident: "BTC-USDT^up"}
+	if err := policy.NotifyDetails("BTC up", "PriceChange^BTC-USDT^up", true, details); err != nil {
+		t.Fatal(err)
+	}
+	if first.count() != 1 || !strings.Contains(first.messages[0], "http://localhost:8080/ack?id=") {
+		t.Fatalf("first step got %q, want the alert with its acknowledgement link", first.messages)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if second.count() != 1 || !strings.HasPrefix(second.messages[0], "[ESCALATED]") {
+		t.Errorf("second step got %q, want the escalated alert", second.messages)
+	}
+}
+
+func TestPolicyStopsEscalatingResolvedAlerts(t *testing.T) {
+	policy, scheduler, first, second := newPolicy(t)
+
+	firing := &notifier.Details{Strategy: "btc", State: "firing", Incident: "BTC-USDT^up"}
+	if err := policy.NotifyDetails("BTC up", "PriceChange^BTC-USDT^up", true, firing); err != nil {
+		t.Fatal(err)
+	}
+	other := &notifier.Details{Strategy: "btc", State: "firing", Incident: "ETH-USDT^up"}
+	if err := policy.NotifyDetails("ETH up", "PriceChange^ETH-USDT^up", true, other); err != nil {
+		t.Fatal(err)
+	}
+	resolved := &notifier.Details{Strategy: "btc", State: notifier.StateResolved, Incident: "BTC-USDT^up"}
+	if err := policy.NotifyDetails("BTC back", "PriceChangeResolved^BTC-USDT^up", true, resolved); err != nil {
+		t.Fatal(err)
+	}
+	if first.count() != 3 {
+		t.Errorf("first step got %d messages, want 3", first.count())
+	}
+	if list := scheduler.List(); len(list) != 1 || list[0].Message != "ETH up" {
+		t.Errorf("pending %v, want only the unresolved alert", list)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if second.count() != 1 || !strings.Contains(second.messages[0], "ETH up") {
+		t.Errorf("second step got %q, want only the unresolved alert", second.messages)
+	}
+}
@@ -0,0 +1,225 @@
+package escalation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pending is an alert waiting to be acknowledged before its policy notifies the next step.
+type Pending struct {
+	ID      string    `json:"id,omitempty"`
+	Policy  string    `json:"policy"`
+	From    string    `json:"from"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+	// Step is the last step notified, and NextAt when the next one is
+	Step   int       `json:"step"`
+	NextAt time.Time `json:"next_at"`
+
+	policy   *Policy
+	incident string
+	details  *notifier.Details
+	timer    *time.Timer
+}
+
+// Scheduler notifies the next step of the alerts nobody acknowledged in time. It outlives
+// config reloads, the policies notify through notifiers which follow reloads.
+// An incident has one escalation per policy, notifying it again updates the escalation.
+type Scheduler struct {
+	mutex   sync.Mutex
+	pending map[string]*Pending
+	// incidents holds the same escalations by policy and incident
+	incidents map[string]*Pending
+	stopped   bool
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{pending: make(map[string]*Pending), incidents: make(map[string]*Pending)}
+}
+
+// add returns the escalation of the incident of an alert by policy, updated with the alert,
+// or a new one which isn't scheduled yet. A copy of it is returned too, for reading it
+// without holding the lock.
+func (s *Scheduler) add(policy *Policy, msg, from string, details *notifier.Details) (*Pending, *Pending) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	incident := incidentOf(from, details)
+	pending, exist := s.incidents[pendingKey(policy.name, incident)]
+	if !exist {
+		id := make([]byte, 8)
+		_, _ = rand.Read(id)
+		pending = &Pending{
+			ID:       hex.EncodeToString(id),
+			Policy:   policy.name,
+			Created:  time.Now(),
+			incident: incident,
+		}
+		if !s.stopped {
+			s.pending[pending.ID] = pending
+			s.incidents[pendingKey(policy.name, incident)] = pending
+		}
+	}
+	pending.From = from
+	pending.Message = msg
+	pending.policy = policy
+	pending.details = details.WithReporter(nil)
+
+	copied := *pending
+	return pending, &copied
+}
+
+func pendingKey(policy, incident string) string {
+	return policy + "\x00" + incident
+}
+
+// incidentOf identifies the alert of a message across its firing and resolved messages, by
+// the incident of its details or else where it's from.
+func incidentOf(from string, details *notifier.Details) string {
+	if len(details.Incident) > 0 {
+		return details.Strategy + "^" + details.Incident
+	}
+	return from
+}
+
+// schedule notifies the step after the last one notified unless acknowledged before, an
+// escalation already scheduled keeps its schedule.
+func (s *Scheduler) schedule(pending *Pending) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pending[pending.ID] == pending && pending.timer == nil {
+		s.scheduleLocked(pending)
+	}
+}
+
+// drop forgets a new escalation which isn't scheduled, e.g. as its first step held the alert back.
+func (s *Scheduler) drop(pending *Pending) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if pending.timer == nil {
+		s.removeLocked(pending)
+	}
+}
+
+func (s *Scheduler) scheduleLocked(pending *Pending) {
+	next := pending.policy.steps[pending.Step+1]
+	pending.NextAt = time.Now().Add(next.after)
+	pending.timer = time.AfterFunc(next.after, func() { s.escalate(pending) })
+}
+
+func (s *Scheduler) removeLocked(pending *Pending) {
+	if pending.timer != nil {
+		pending.timer.Stop()
+	}
+	delete(s.pending, pending.ID)
+	if s.incidents[pendingKey(pending.Policy, pending.incident)] == pending {
+		delete(s.incidents, pendingKey(pending.Policy, pending.incident))
+	}
+}
+
+func (s *Scheduler) escalate(pending *Pending) {
+	s.mutex.Lock()
+	if s.pending[pending.ID] != pending || s.stopped {
+		s.mutex.Unlock()
+		return
+	}
+	pending.Step++
+	step := pending.Step
+	copied := *pending
+	s.mutex.Unlock()
+
+	log.Infof("alert %s from %s not acknowledged, escalating to step %d of %s", copied.ID, copied.From, step+1, copied.Policy)
+	if err := copied.policy.notifyStep(&copied, step); err != nil {
+		log.Warnf("escalation of alert %s failed: %v", copied.ID, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.pending[pending.ID] != pending || s.stopped {
+		// acknowledged in the meantime
+		return
+	}
+	if step+1 < len(pending.policy.steps) {
+		s.scheduleLocked(pending)
+	} else {
+		s.removeLocked(pending)
+	}
+}
+
+// resolve stops the escalation of a policy for an incident which resolved.
+func (s *Scheduler) resolve(policy, incident string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if pending, exist := s.incidents[pendingKey(policy, incident)]; exist {
+		s.removeLocked(pending)
+		log.Infof("alert %s from %s resolved, stopping its escalation", pending.ID, pending.From)
+	}
+}
+
+// Get returns a copy of the alert of id if it's waiting to be acknowledged.
+func (s *Scheduler) Get(id string) (*Pending, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending, exist := s.pending[id]
+	if !exist {
+		return nil, false
+	}
+	copied := *pending
+	return &copied, true
+}
+
+// Ack acknowledges an alert, stopping the escalations of its incident by every policy, and
+// returns it if it was pending.
+func (s *Scheduler) Ack(id string) (*Pending, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending, exist := s.pending[id]
+	if !exist {
+		return nil, false
+	}
+	for _, other := range s.pending {
+		if other.incident == pending.incident {
+			s.removeLocked(other)
+		}
+	}
+	log.Infof("alert %s from %s acknowledged", id, pending.From)
+	copied := *pending
+	return &copied, true
+}
+
+// List returns copies of the alerts waiting to be acknowledged by creation.
+func (s *Scheduler) List() []*Pending {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := make([]*Pending, 0, len(s.pending))
+	for _, pending := range s.pending {
+		copied := *pending
+		list = append(list, &copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
+
+// Stop drops the pending escalations.
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	for _, pending := range s.pending {
+		s.removeLocked(pending)
+	}
+}
@@ -0,0 +1,89 @@
+package escalation
+
+import (
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedulerKeysEscalationsByIncident(t *testing.T) {
+	first, second, other := &recorder{}, &recorder{}, &recorder{}
+	scheduler := NewScheduler()
+	defer scheduler.Stop()
+	steps := func(last string) []*config.EscalationStep {
+		return []*config.EscalationStep{{Notifiers: []string{"first"}}, {Notifiers: []string{last}, After: "50ms"}}
+	}
+	conf := &config.Escalation{Policies: []*config.EscalationPolicy{
+		{Name: "oncall", Steps: steps("second")},
+		{Name: "backup", Steps: steps("other")},
+	}}
+	notifiers := map[string]notifier.Notifier{"first": first, "second": second, "other": other}
+	policies, err := NewPolicies(conf, "http://monitor.example.com", notifiers, scheduler, templates.TextsOf(templates.LocaleEn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	details := &notifier.Details{Strategy: "btc", State: "firing", Incident: "BTC-USDT^up"}
+	for _, msg := range []string{"BTC up", "BTC up again", "BTC up once more"} {
+		if err := policies["oncall"].NotifyDetails(msg, "PriceChange^BTC-USDT^up", false, details); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := policies["backup"].NotifyDetails("BTC up", "PriceChange^BTC-USDT^up", false, details); err != nil {
+		t.Fatal(err)
+	}
+
+	list := scheduler.List()
+	if len(list) != 2 {
+		t.Fatalf("got %d escalations, want one by policy", len(list))
+	}
+	var oncall *Pending
+	for _, pending := range list {
+		if pending.Policy == "oncall" {
+			oncall = pending
+		}
+	}
+	if oncall == nil || oncall.Message != "BTC up once more" {
+		t.Fatalf("oncall escalation %+v, want it updated by the last alert", oncall)
+	}
+	for _, msg := range first.messages[:3] {
+		if !strings.Contains(msg, "/ack?id="+oncall.ID) {
+			t.Errorf("message %q doesn't link the escalation of the incident", msg)
+		}
+	}
+
+	if _, exist := scheduler.Ack(oncall.ID); !exist {
+		t.Fatal("escalation not found")
+	}
+	if list := scheduler.List(); len(list) != 0 {
+		t.Errorf("escalations %v still pending after acknowledging the incident", list)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if second.count() != 0 || other.count() != 0 {
+		t.Errorf("escalated %d and %d times after the acknowledgement", second.count(), other.count())
+	}
+}
+
+func TestSchedulerKeepsEscalationOnRenotify(t *testing.T) {
+	policy, scheduler, _, second := newPolicy(t)
+
+	details := &notifier.Details{Strategy: "btc", State: "firing", Incident: "BTC-USDT^up"}
+	if err := policy.NotifyDetails("BTC up", "PriceChange^BTC-USDT^up", false, details); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	// notifying again doesn't postpone the escalation
+	if err := policy.NotifyDetails("BTC up again", "PriceChange^BTC-USDT^up", false, details); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if second.count() != 1 || !strings.Contains(second.messages[0], "BTC up again") {
+		t.Errorf("second step got %q, want the updated alert once", second.messages)
+	}
+	if list := scheduler.List(); len(list) != 0 {
+		t.Errorf("escalations %v pending after the last step", list)
+	}
+}
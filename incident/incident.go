@@ -0,0 +1,171 @@
+package incident
+
+import (
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/state"
+	"sync"
+	"time"
+)
+
+// State is the stage of an incident: pending while its condition holds for less than the
+// lifecycle's For duration, firing once it held long enough, and resolved once it cleared.
+type State int
+
+const (
+	StatePending State = iota
+	StateFiring
+	StateResolved
+)
+
+var stateNames = []string{"pending", "firing", "resolved"}
+
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("state(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+// Lifecycle configures when incidents fire and how often they're notified.
+type Lifecycle struct {
+	// For is how long a condition must hold before its incident fires, right away if zero.
+	For time.Duration
+	// RepeatInterval is how often a firing incident is notified again.
+	RepeatInterval time.Duration
+	// Cooldown is the least time between two notifications of a condition on a pair, even
+	// if its incident resolved and fired again in between.
+	Cooldown time.Duration
+	// Step notifies a firing incident again as soon as its value grew by step since it
+	// was last notified, despite the repeat interval and cooldown. Disabled if zero.
+	Step float64
+	// SendResolved notifies when a firing incident resolves.
+	SendResolved bool
+}
+
+// Incident is a condition of a strategy holding on a pair of an exchange.
+type Incident struct {
+	Strategy  string
+	Exchange  string
+	Pair      string
+	Condition string
+
+	State      State
+	ActiveAt   time.Time
+	FiredAt    time.Time
+	ResolvedAt time.Time
+	// NotifiedAt is when the incident was last notified, zero if it never was
+	NotifiedAt time.Time
+
+	// Value is the last magnitude of the condition, and NotifiedValue the one last notified
+	Value         float64
+	NotifiedValue float64
+}
+
+func (i *Incident) Key() string {
+	return incidentKey(i.Exchange, i.Pair, i.Condition)
+}
+
+func incidentKey(exchange, pair, condition string) string {
+	if len(exchange) == 0 {
+		return pair + "/" + condition
+	}
+	return exchange + "/" + pair + "/" + condition
+}
+
+// Manager moves the incidents of a strategy through their lifecycle. The repeat interval and
+// cooldown of each condition are kept in the state store, so restarts don't notify them
+// again early.
+type Manager struct {
+	strategy  string
+	lifecycle *Lifecycle
+
+	mutex     sync.Mutex
+	incidents map[string]*Incident
+	notified  *state.Cache
+}
+
+func NewManager(strategy string, lifecycle *Lifecycle, notified *state.Cache) *Manager {
+	return &Manager{
+		strategy:  strategy,
+		lifecycle: lifecycle,
+		incidents: make(map[string]*Incident),
+		notified:  notified,
+	}
+}
+
+// Update moves the incident of a condition on a pair of an exchange on by whether the condition
+// holds at now, with value the magnitude it holds by. It returns a copy of the incident, or nil
+// if there is none, and whether to notify it. Only incidents notified while firing are
+// notified once resolved, and the repeat interval outlives them, so a condition flapping
+// around its threshold is notified once per interval.
+func (m *Manager) Update(exchange, pair, condition string, holds bool, value float64, now time.Time) (*Incident, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := incidentKey(exchange, pair, condition)
+	current, exist := m.incidents[key]
+	if !holds {
+		if !exist {
+			return nil, false
+		}
+		delete(m.incidents, key)
+		if current.State == StatePending {
+			return nil, false
+		}
+
+		current.State = StateResolved
+		current.ResolvedAt = now
+		current.Value = value
+		resolved := *current
+		return &resolved, m.lifecycle.SendResolved && !current.NotifiedAt.IsZero()
+	}
+
+	if !exist {
+		current = &Incident{
+			Strategy:  m.strategy,
+			Exchange:  exchange,
+			Pair:      pair,
+			Condition: condition,
+			State:     StatePending,
+			ActiveAt:  now,
+		}
+		m.incidents[key] = current
+	}
+	current.Value = value
+	if current.State == StatePending && now.Sub(current.ActiveAt) >= m.lifecycle.For {
+		current.State = StateFiring
+		current.FiredAt = now
+		if m.notified.Has(key) || m.notified.Has(cooldownKey(key)) {
+			// notified before a restart or within the cooldown, steps count from now on
+			current.NotifiedValue = value
+		}
+	}
+
+	notify := false
+	if current.State == StateFiring {
+		notify = m.due(key, current)
+	}
+	if notify {
+		current.NotifiedValue = value
+		current.NotifiedAt = now
+		m.notified.Set(key, m.lifecycle.RepeatInterval)
+		if m.lifecycle.Cooldown > 0 {
+			m.notified.Set(cooldownKey(key), m.lifecycle.Cooldown)
+		}
+	}
+
+	updated := *current
+	return &updated, notify
+}
+
+// due reports whether a firing incident must be notified.
+func (m *Manager) due(key string, current *Incident) bool {
+	if m.lifecycle.Step > 0 && current.NotifiedValue > 0 && current.Value >= current.NotifiedValue+m.lifecycle.Step {
+		return true
+	}
+	return !m.notified.Has(key) && !m.notified.Has(cooldownKey(key))
+}
+
+func cooldownKey(key string) string {
+	return "cooldown/" + key
+}
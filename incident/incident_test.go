@@ -0,0 +1,150 @@
+package incident
+
+import (
+	"github.com/azraeljack/crypto-monitor/state"
+	"testing"
+	"time"
+)
+
+// none stands for no incident returned by Update.
+const none State = -1
+
+type update struct {
+	exchange  string
+	condition string
+	holds     bool
+	value     float64
+	after     time.Duration
+
+	state  State
+	notify bool
+}
+
+func TestManagerUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		lifecycle Lifecycle
+		updates   []update
+	}{
+		{
+			name:      "fires and resolves",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour, SendResolved: true},
+			updates: []update{
+				{condition: "up", holds: false, state: none},
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: true, value: 5, state: StateFiring},
+				{condition: "up", holds: false, state: StateResolved, notify: true},
+				{condition: "up", holds: false, state: none},
+			},
+		},
+		{
+			name:      "resolved without notifying",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: false, state: StateResolved},
+			},
+		},
+		{
+			name:      "pending for a while",
+			lifecycle: Lifecycle{For: time.Minute, RepeatInterval: time.Hour, SendResolved: true},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StatePending},
+				{condition: "up", holds: true, value: 5, after: 30 * time.Second, state: StatePending},
+				{condition: "up", holds: false, after: 40 * time.Second, state: none},
+				{condition: "up", holds: true, value: 5, after: 50 * time.Second, state: StatePending},
+				{condition: "up", holds: true, value: 5, after: 110 * time.Second, state: StateFiring, notify: true},
+			},
+		},
+		{
+			name:      "flapping within the repeat interval",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour, SendResolved: true},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: false, state: StateResolved, notify: true},
+				{condition: "up", holds: true, value: 5, state: StateFiring},
+				{condition: "up", holds: false, state: StateResolved},
+				{condition: "up", holds: true, value: 5, state: StateFiring},
+			},
+		},
+		{
+			name:      "repeats once the interval passed",
+			lifecycle: Lifecycle{RepeatInterval: time.Nanosecond},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+			},
+		},
+		{
+			name:      "cooldown outlasts the repeat interval",
+			lifecycle: Lifecycle{RepeatInterval: time.Nanosecond, Cooldown: time.Hour, SendResolved: true},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: true, value: 5, state: StateFiring},
+				{condition: "up", holds: false, state: StateResolved, notify: true},
+				{condition: "up", holds: true, value: 5, state: StateFiring},
+			},
+		},
+		{
+			name:      "step thresholds",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour, Cooldown: time.Hour, Step: 2},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: true, value: 6.5, state: StateFiring},
+				{condition: "up", holds: true, value: 7, state: StateFiring, notify: true},
+				{condition: "up", holds: true, value: 8, state: StateFiring},
+				{condition: "up", holds: true, value: 9.5, state: StateFiring, notify: true},
+			},
+		},
+		{
+			name:      "steps count from a refire within the cooldown",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour, Cooldown: time.Hour, Step: 2},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: false, state: StateResolved},
+				{condition: "up", holds: true, value: 3, state: StateFiring},
+				{condition: "up", holds: true, value: 4, state: StateFiring},
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+			},
+		},
+		{
+			name:      "rises and falls are separate",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour, SendResolved: true},
+			updates: []update{
+				{condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "down", holds: false, state: none},
+				{condition: "down", holds: true, value: 5, state: StateFiring, notify: true},
+				{condition: "up", holds: false, state: StateResolved, notify: true},
+				{condition: "down", holds: true, value: 5, state: StateFiring},
+			},
+		},
+		{
+			name:      "exchanges are separate",
+			lifecycle: Lifecycle{RepeatInterval: time.Hour, SendResolved: true},
+			updates: []update{
+				{exchange: "binance", condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{exchange: "okx", condition: "up", holds: false, state: none},
+				{exchange: "okx", condition: "up", holds: true, value: 5, state: StateFiring, notify: true},
+				{exchange: "binance", condition: "up", holds: true, value: 5, state: StateFiring},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lifecycle := test.lifecycle
+			manager := NewManager("test", &lifecycle, (*state.Store)(nil).Cache("incidents"))
+			start := time.Now()
+			for i, u := range test.updates {
+				current, notify := manager.Update(u.exchange, "BTC-USDT", u.condition, u.holds, u.value, start.Add(u.after))
+				state := none
+				if current != nil {
+					state = current.State
+				}
+				if state != u.state || notify != u.notify {
+					t.Errorf("update %d: got %v, notify %v, want %v, notify %v", i, state, notify, u.state, u.notify)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"time"
+)
+
+const (
+	StatusSent      = "sent"
+	StatusThrottled = "throttled"
+	StatusFailed    = "failed"
+	StatusSilenced  = "silenced"
+)
+
+// Entry is an alert fired by a strategy along with its delivery through each notifier.
+type Entry struct {
+	Time     time.Time              `json:"time"`
+	Strategy string                 `json:"strategy"`
+	Pair     string                 `json:"pair,omitempty"`
+	Severity string                 `json:"severity"`
+	State    string                 `json:"state,omitempty"`
+	From     string                 `json:"from"`
+	Message  string                 `json:"message"`
+	Price    *collector.WindowPrice `json:"price,omitempty"`
+	// Silenced describes the silence which suppressed the alert, if any
+	Silenced   *silence.Silence `json:"silenced,omitempty"`
+	Deliveries []*Delivery      `json:"deliveries"`
+}
+
+type Delivery struct {
+	Notifier string `json:"notifier"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (e *Entry) ToJSON() []byte {
+	data, _ := json.Marshal(e)
+	return data
+}
+
+// NewDelivery returns the delivery of a notifier which returned err.
+func NewDelivery(name string, err error) *Delivery {
+	switch {
+	case err == nil:
+		return &Delivery{Notifier: name, Status: StatusSent}
+	case errors.Is(err, notifier.ErrThrottled):
+		return &Delivery{Notifier: name, Status: StatusThrottled}
+	case errors.Is(err, notifier.ErrSilenced):
+		return &Delivery{Notifier: name, Status: StatusSilenced}
+	default:
+		return &Delivery{Notifier: name, Status: StatusFailed, Error: err.Error()}
+	}
+}
+
+// Filter selects alerts by time range, pair and strategy, empty fields match every alert.
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	Pair     string
+	Strategy string
+
+	// Limit keeps only the latest alerts if positive
+	Limit int
+}
+
+func (f *Filter) Match(e *Entry) bool {
+	return (f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until)) &&
+		(len(f.Pair) == 0 || e.Pair == f.Pair) &&
+		(len(f.Strategy) == 0 || e.Strategy == f.Strategy)
+}
+
+// ParseTime parses a query time, either absolute as RFC 3339 or "2006-01-02 15:04[:05]"
+// in local time, or relative to now as a duration ago, e.g. "2h".
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid time " + value + ", expecting RFC 3339, \"2006-01-02 15:04\" or a duration ago")
+}
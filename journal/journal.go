@@ -0,0 +1,247 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("alert journal is not open")
+
+const (
+	// maxLine bounds the length of a journaled alert
+	maxLine = 4 * 1024 * 1024
+	// pruneInterval is how often alerts past retention are pruned while recording
+	pruneInterval = time.Hour
+)
+
+// Journal appends alerts to a file as JSON lines, each synced to disk before returning
+// so the journal survives crashes.
+type Journal struct {
+	mutex     sync.Mutex
+	file      *os.File
+	path      string
+	retention time.Duration
+	prunedAt  time.Time
+}
+
+// New returns a closed journal, recording does nothing until it's opened.
+func New() *Journal {
+	return &Journal{}
+}
+
+// Open opens the journal file after pruning the alerts past retention.
+func (j *Journal) Open(settings *Settings) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file != nil {
+		return errors.New("alert journal already open")
+	}
+
+	j.path = settings.Path
+	j.retention = settings.Retention
+	if err := j.prune(time.Now()); err != nil {
+		log.Warnf("failed to prune alert journal %s: %v", j.path, err)
+	}
+
+	file, err := os.OpenFile(settings.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open alert journal: %w", err)
+	}
+	j.file = file
+	return nil
+}
+
+// Configure applies a new retention, the path only changes on reopen.
+func (j *Journal) Configure(settings *Settings) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.retention = settings.Retention
+}
+
+// Path returns the path of the open journal, or an empty string if closed.
+func (j *Journal) Path() string {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return ""
+	}
+	return j.path
+}
+
+func (j *Journal) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Record appends an alert, it does nothing if the journal is closed or nil.
+func (j *Journal) Record(e *Entry) error {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	if _, err := j.file.Write(append(e.ToJSON(), '\n')); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+
+	if now := time.Now(); j.retention > 0 && now.Sub(j.prunedAt) >= pruneInterval {
+		if err := j.prune(now); err != nil {
+			log.Warnf("failed to prune alert journal %s: %v", j.path, err)
+		}
+	}
+	return nil
+}
+
+// prune rewrites the journal without the alerts recorded before retention, and reopens it
+// if it was open. It does nothing without retention or alerts to prune.
+func (j *Journal) prune(now time.Time) error {
+	if j.retention <= 0 {
+		return nil
+	}
+	j.prunedAt = now
+	cutoff := now.Add(-j.retention)
+
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	kept := &bytes.Buffer{}
+	pruned := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	for scanner.Scan() {
+		e := &Entry{}
+		// partial lines left by a crash are pruned as well
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil || e.Time.Before(cutoff) {
+			pruned++
+			continue
+		}
+		kept.Write(scanner.Bytes())
+		kept.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if pruned == 0 {
+		return nil
+	}
+
+	tmp := j.path + ".tmp"
+	if err := writeFileSync(tmp, kept.Bytes()); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	log.Infof("pruned %d alerts recorded before %v from the journal", pruned, cutoff.Format(time.RFC3339))
+
+	if j.file == nil {
+		return nil
+	}
+	reopened, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_ = j.file.Close()
+	j.file = reopened
+	return nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Query returns the recorded alerts matching filter.
+func (j *Journal) Query(filter *Filter) ([]*Entry, error) {
+	path := j.Path()
+	if len(path) == 0 {
+		return nil, ErrClosed
+	}
+	return Query(path, filter)
+}
+
+// Query reads the alerts matching filter from the journal file at path, in time order.
+func Query(path string, filter *Filter) ([]*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open alert journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	for scanner.Scan() {
+		e := &Entry{}
+		// a crash may leave a partial last line
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+type journalKey struct{}
+
+// NewContext returns a context carrying the journal for the components built with it.
+func NewContext(ctx context.Context, j *Journal) context.Context {
+	return context.WithValue(ctx, journalKey{}, j)
+}
+
+// FromContext returns the journal of ctx, or nil if it carries none.
+func FromContext(ctx context.Context) *Journal {
+	j, _ := ctx.Value(journalKey{}).(*Journal)
+	return j
+}
@@ -0,0 +1,135 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func openJournal(t *testing.T, settings *Settings) *Journal {
+	t.Helper()
+	j := New()
+	if err := j.Open(settings); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = j.Close() })
+	return j
+}
+
+func strategies(entries []*Entry) []string {
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Strategy)
+	}
+	return names
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j := openJournal(t, &Settings{Path: path})
+
+	now := time.Now()
+	for _, e := range []*Entry{
+		{Time: now.Add(-3 * time.Hour), Strategy: "btc", Pair: "BTC-USDT"},
+		{Time: now.Add(-2 * time.Hour), Strategy: "eth", Pair: "ETH-USDT"},
+		{Time: now.Add(-time.Hour), Strategy: "btc", Pair: "BTC-USDT"},
+		{Time: now, Strategy: "btc-fast", Pair: "BTC-USDT"},
+	} {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a crash may leave a partial last line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"time": "`)
+	_ = file.Close()
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   []string
+	}{
+		{name: "every alert", filter: &Filter{}, want: []string{"btc", "eth", "btc", "btc-fast"}},
+		{name: "strategy", filter: &Filter{Strategy: "btc"}, want: []string{"btc", "btc"}},
+		{name: "pair", filter: &Filter{Pair: "ETH-USDT"}, want: []string{"eth"}},
+		{name: "since", filter: &Filter{Since: now.Add(-time.Hour)}, want: []string{"btc", "btc-fast"}},
+		{name: "until", filter: &Filter{Until: now.Add(-time.Hour)}, want: []string{"btc", "eth"}},
+		{name: "limit keeps the latest", filter: &Filter{Pair: "BTC-USDT", Limit: 2}, want: []string{"btc", "btc-fast"}},
+	}
+	for _, test := range tests {
+		entries, err := j.Query(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strategies(entries); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestClosedJournal(t *testing.T) {
+	j := New()
+	if err := j.Record(&Entry{Strategy: "btc"}); err != nil {
+		t.Errorf("recording in a closed journal failed: %v", err)
+	}
+	if _, err := j.Query(&Filter{}); err != ErrClosed {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	now := time.Now()
+	j := openJournal(t, &Settings{Path: path})
+	for _, e := range []*Entry{
+		{Time: now.Add(-48 * time.Hour), Strategy: "old"},
+		{Time: now.Add(-2 * time.Hour), Strategy: "recent"},
+	} {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// pruned on open
+	j = openJournal(t, &Settings{Path: path, Retention: 24 * time.Hour})
+	entries, err := j.Query(&Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strategies(entries); len(got) != 1 || got[0] != "recent" {
+		t.Fatalf("kept %v after opening, want only the recent alert", got)
+	}
+
+	// and while recording once the prune interval passed
+	j.Configure(&Settings{Path: path, Retention: time.Hour})
+	if err := j.Record(&Entry{Time: now, Strategy: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	j.prunedAt = now.Add(-pruneInterval)
+	if err := j.Record(&Entry{Time: now, Strategy: "second"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = j.Query(&Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strategies(entries); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("kept %v after recording, want the alerts within the new retention", got)
+	}
+
+	// the reopened file is still appended to
+	if err := j.Record(&Entry{Time: now, Strategy: "third"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := j.Query(&Filter{}); len(entries) != 3 {
+		t.Errorf("got %d alerts after pruning, want 3", len(entries))
+	}
+}
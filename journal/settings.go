@@ -0,0 +1,23 @@
+package journal
+
+import (
+	"github.com/azraeljack/crypto-monitor/config"
+	"time"
+)
+
+type Settings struct {
+	Path      string
+	Retention time.Duration
+}
+
+// ParseSettings validates the journal config.
+func ParseSettings(conf *config.Journal) (*Settings, error) {
+	errs := config.Errors{}
+	config.Require(&errs, "path", conf.Path)
+
+	settings := &Settings{
+		Path:      conf.Path,
+		Retention: config.ParseDuration(&errs, "retention", conf.Retention, 0),
+	}
+	return settings, errs.Err()
+}
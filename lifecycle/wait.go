@@ -0,0 +1,22 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// Wait blocks until wg is done or ctx is, in which case ctx's error is returned.
+func Wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -12,6 +12,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// SetupLogRotate logs to stderr and monitor.log in the working directory, which is rotated by
+// size and on SIGUSR1. SIGHUP only reopens it, so a log moved away by logrotate is recreated.
 func SetupLogRotate() {
 	currentDir, _ := os.Getwd()
 
@@ -34,15 +36,18 @@ func SetupLogRotate() {
 	log.SetLevel(log.DebugLevel)
 	log.SetOutput(multiWriter)
 
+	// SIGHUP reloads the config as well, so it must not rotate away the backups
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGHUP)
+	signal.Notify(c, syscall.SIGHUP, syscall.SIGUSR1)
 
 	go func() {
-		for {
-			<-c
-			if err := lumberjackLogger.Rotate(); err != nil {
-				return
+		for sig := range c {
+			if sig == syscall.SIGUSR1 {
+				_ = lumberjackLogger.Rotate()
+				continue
 			}
+			// the next write opens the file again
+			_ = lumberjackLogger.Close()
 		}
 	}()
 }
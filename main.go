@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/journal"
 	"github.com/azraeljack/crypto-monitor/logging"
 	_ "github.com/azraeljack/crypto-monitor/logging"
 	"github.com/azraeljack/crypto-monitor/monitor"
@@ -10,13 +12,52 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
 	debug := flag.Bool("debug", false, "enable debug mode")
-	config := flag.String("config", "./config.json", "monitor config json file path")
+	config := flag.String("config", "./config.json", "monitor config file (json, yaml or toml) or directory of config files")
+	validate := flag.Bool("validate", false, "validate the config and exit")
+	watch := flag.Duration("watch", 5*time.Second, "interval to check the config file for changes to reload, 0 to disable")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "time to wait for pending notifications on shutdown")
+	alerts := flag.Bool("alerts", false, "print the alerts of the journal as JSON lines and exit")
+	since := flag.String("since", "", "with -alerts, only alerts since a time (RFC 3339, \"2006-01-02 15:04\") or a duration ago")
+	until := flag.String("until", "", "with -alerts, only alerts before a time or a duration ago")
+	pair := flag.String("pair", "", "with -alerts or -snooze, only alerts of a symbol pair, e.g. BTC-USDT")
+	strategy := flag.String("strategy", "", "with -alerts or -snooze, only alerts of a strategy")
+	limit := flag.Int("limit", 0, "with -alerts, only the latest alerts, 0 for all")
+	snooze := flag.Duration("snooze", 0, "silence the alerts of the running monitor for a duration and exit, "+
+		"only the ones of -strategy and -pair if given")
 	flag.Parse()
 
+	if *validate {
+		if err := monitor.Validate(*config); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config %s:\n%v\n", *config, err)
+			os.Exit(1)
+		}
+		fmt.Printf("config %s is valid\n", *config)
+		return
+	}
+
+	if *alerts {
+		if err := printAlerts(*config, *since, *until, *pair, *strategy, *limit); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to query alerts: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *snooze > 0 {
+		silenced, err := monitor.Snooze(*config, *strategy, *pair, *snooze)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to snooze: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("alerts snoozed until %s\n", silenced.Until.Local().Format("2006-01-02 15:04:05"))
+		return
+	}
+
 	if !*debug {
 		logging.SetupLogRotate()
 	} else {
@@ -28,16 +69,63 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	mon := monitor.NewMonitor(ctx, *config)
-	mon.Start()
+	mon, err := monitor.NewMonitor(ctx, *config)
+	if err != nil {
+		log.Fatalf("invalid config:\n%v", err)
+	}
+	if err := mon.Start(); err != nil {
+		log.Fatalf("failed to start the monitor: %v", err)
+	}
+	if *watch > 0 {
+		mon.Watch(*watch)
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGHUP, syscall.SIGKILL, syscall.SIGINT)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 
-	<-c
-	<-mon.Stop()
+	for running := true; running; {
+		select {
+		case <-reload:
+			log.Info("received SIGHUP, reloading config...")
+			_ = mon.Reload()
+		case <-c:
+			running = false
+		}
+	}
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	if err := mon.Stop(stopCtx); err != nil {
+		log.Warnf("monitor didn't stop cleanly: %v", err)
+	}
+	stopCancel()
 
 	cancel()
 
 	log.Info("monitor exited")
 }
+
+// printAlerts queries the alert journal of the config.
+func printAlerts(configFile, since, until, pair, strategy string, limit int) error {
+	now := time.Now()
+	filter := &journal.Filter{Pair: pair, Strategy: strategy, Limit: limit}
+
+	var err error
+	if filter.Since, err = journal.ParseTime(since, now); err != nil {
+		return err
+	}
+	if filter.Until, err = journal.ParseTime(until, now); err != nil {
+		return err
+	}
+
+	entries, err := monitor.QueryAlerts(configFile, filter)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Println(string(e.ToJSON()))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const namespace = "crypto_monitor"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	collectorRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "collector_request_duration_seconds",
+		Help:      "Latency of the requests collectors send to exchanges.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"collector", "endpoint"})
+	collectorRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_request_errors_total",
+		Help:      "Failed requests of collectors to exchanges.",
+	}, []string{"collector", "endpoint"})
+	collectorPrices = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_prices_total",
+		Help:      "Prices received by collectors.",
+	}, []string{"collector", "pair"})
+	collectorDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_dropped_prices_total",
+		Help:      "Prices dropped by collectors because the strategy didn't keep up.",
+	}, []string{"collector", "pair"})
+
+	strategyEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "strategy_evaluations_total",
+		Help:      "Prices evaluated by strategies.",
+	}, []string{"strategy", "pair"})
+	strategyMatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "strategy_matches_total",
+		Help:      "Prices matching the conditions of strategies.",
+	}, []string{"strategy", "pair", "severity"})
+	strategyDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "strategy_dropped_matches_total",
+		Help:      "Matched prices dropped by strategies because the notifications didn't keep up.",
+	}, []string{"strategy", "pair"})
+
+	notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notifications by notifier and result, one of sent, queued, throttled, silenced or failed.",
+	}, []string{"notifier", "result"})
+	notificationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "notification_duration_seconds",
+		Help:      "Time notifiers took to deliver notifications.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"notifier"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectorRequestDuration, collectorRequestErrors, collectorPrices, collectorDropped,
+		strategyEvaluations, strategyMatches, strategyDropped,
+		notifications, notificationDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a request a collector started at start to an exchange endpoint.
+func ObserveRequest(collector, endpoint string, start time.Time, err error) {
+	collectorRequestDuration.WithLabelValues(collector, endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		collectorRequestErrors.WithLabelValues(collector, endpoint).Inc()
+	}
+}
+
+func PriceReceived(collector, pair string) {
+	collectorPrices.WithLabelValues(collector, pair).Inc()
+}
+
+func PriceDropped(collector, pair string) {
+	collectorDropped.WithLabelValues(collector, pair).Inc()
+}
+
+func Evaluated(strategy, pair string) {
+	strategyEvaluations.WithLabelValues(strategy, pair).Inc()
+}
+
+func Matched(strategy, pair, severity string) {
+	strategyMatches.WithLabelValues(strategy, pair, severity).Inc()
+}
+
+func MatchDropped(strategy, pair string) {
+	strategyDropped.WithLabelValues(strategy, pair).Inc()
+}
+
+// Notified records a notification a notifier started at start, result is one of sent, queued,
+// throttled, silenced or failed.
+func Notified(notifier, result string, start time.Time) {
+	notifications.WithLabelValues(notifier, result).Inc()
+	if result != "queued" && result != "throttled" && result != "silenced" {
+		notificationDuration.WithLabelValues(notifier).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ForgetCollector, ForgetStrategy and ForgetNotifier drop the series of a removed component.
+func ForgetCollector(name string) {
+	labels := prometheus.Labels{"collector": name}
+	collectorRequestDuration.DeletePartialMatch(labels)
+	collectorRequestErrors.DeletePartialMatch(labels)
+	collectorPrices.DeletePartialMatch(labels)
+	collectorDropped.DeletePartialMatch(labels)
+}
+
+func ForgetStrategy(name string) {
+	labels := prometheus.Labels{"strategy": name}
+	strategyEvaluations.DeletePartialMatch(labels)
+	strategyMatches.DeletePartialMatch(labels)
+	strategyDropped.DeletePartialMatch(labels)
+}
+
+func ForgetNotifier(name string) {
+	labels := prometheus.Labels{"notifier": name}
+	notifications.DeletePartialMatch(labels)
+	notificationDuration.DeletePartialMatch(labels)
+}
@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"errors"
+	"github.com/azraeljack/crypto-monitor/journal"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/silence"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// dispatcher delivers the alerts of a strategy to its notifiers concurrently, unless they are
+// silenced, and records each alert along with the outcome of every notifier in the journal.
+type dispatcher struct {
+	strategy string
+	targets  []notifier.Notifier
+	journal  *journal.Journal
+	silencer *silence.Silencer
+}
+
+func (d *dispatcher) Notify(msg, from string, throttle bool) error {
+	return d.NotifyDetails(msg, from, throttle, &notifier.Details{Strategy: d.strategy})
+}
+
+// NotifyDetails fails only if no notifier delivered the alert, returning the first error, or
+// ErrSilenced if a silence matches it.
+func (d *dispatcher) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	entry := &journal.Entry{
+		Time:     time.Now(),
+		Strategy: d.strategy,
+		Severity: details.Severity.String(),
+		State:    details.State,
+		From:     from,
+		Message:  msg,
+		Price:    details.Price,
+	}
+	if details.Price != nil {
+		entry.Pair = details.Price.SymbolPair()
+	}
+
+	if entry.Silenced = d.silencer.Match(d.strategy, entry.Pair); entry.Silenced != nil {
+		log.Infof("alert from %s silenced until %v", from, entry.Silenced.Until.Format(time.RFC3339))
+		if err := d.journal.Record(entry); err != nil {
+			log.Errorf("failed to journal alert from %s: %v", from, err)
+		}
+		return notifier.ErrSilenced
+	}
+
+	outcomes := &outcomes{journal: d.journal, entry: entry, awaiting: make(map[string]int)}
+	reported := details.WithReporter(outcomes.report)
+
+	errs := make([]error, len(d.targets))
+	wg := sync.WaitGroup{}
+	for i, target := range d.targets {
+		wg.Add(1)
+		go func(i int, target notifier.Notifier) {
+			defer wg.Done()
+			errs[i] = notifier.Send(target, msg, from, throttle, reported)
+		}(i, target)
+	}
+	wg.Wait()
+	outcomes.returned()
+
+	return firstError(errs)
+}
+
+// outcomes collects the outcome of every notifier delivering an alert, and journals the alert
+// once the notifiers that grouped it reported how its group was delivered too.
+type outcomes struct {
+	mutex   sync.Mutex
+	journal *journal.Journal
+	entry   *journal.Entry
+	// awaiting counts the grouped deliveries of each notifier, done is set once every
+	// notifier returned
+	awaiting map[string]int
+	done     bool
+}
+
+func (o *outcomes) report(name string, err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if errors.Is(err, notifier.ErrGrouped) {
+		o.awaiting[name]++
+		return
+	}
+	o.entry.Deliveries = append(o.entry.Deliveries, journal.NewDelivery(name, err))
+	if o.awaiting[name] > 0 {
+		o.awaiting[name]--
+		if o.awaiting[name] == 0 {
+			delete(o.awaiting, name)
+		}
+		o.recordIfComplete()
+	}
+}
+
+func (o *outcomes) returned() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.done = true
+	o.recordIfComplete()
+}
+
+func (o *outcomes) recordIfComplete() {
+	if !o.done || len(o.awaiting) > 0 {
+		return
+	}
+	if err := o.journal.Record(o.entry); err != nil {
+		log.Errorf("failed to journal alert from %s: %v", o.entry.From, err)
+	}
+}
+
+// broadcast sends msg to every notifier, bypassing silences and the journal.
+func (d *dispatcher) broadcast(msg, from string) {
+	for _, target := range d.targets {
+		go func(target notifier.Notifier) {
+			_ = target.Notify(msg, from, false)
+		}(target)
+	}
+}
+
+// firstError returns nil if any of errs is nil, otherwise the first of them.
+func firstError(errs []error) error {
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
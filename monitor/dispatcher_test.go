@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"errors"
+	"github.com/azraeljack/crypto-monitor/journal"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/status"
+	"path/filepath"
+	"testing"
+)
+
+// queueingNotifier queues every message, like a digest, until sent.
+type queueingNotifier struct {
+	queued []*notifier.Details
+}
+
+func (q *queueingNotifier) Notify(msg, from string, throttle bool) error {
+	return q.NotifyDetails(msg, from, throttle, nil)
+}
+
+func (q *queueingNotifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	details.Report("queue", notifier.ErrGrouped)
+	q.queued = append(q.queued, details)
+	return notifier.ErrGrouped
+}
+
+func (q *queueingNotifier) send(err error) {
+	for _, details := range q.queued {
+		details.Report("queue", err)
+	}
+	q.queued = nil
+}
+
+func TestQueuedAlertsAreJournaledOnceSent(t *testing.T) {
+	j := journal.New()
+	if err := j.Open(&journal.Settings{Path: filepath.Join(t.TempDir(), "journal.log")}); err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	silencer := silence.NewSilencer(state.New().Cache("silences"), nil)
+	defer silencer.Stop()
+
+	queue := &queueingNotifier{}
+	d := &dispatcher{
+		strategy: "btc",
+		targets:  []notifier.Notifier{&trackedNotifier{name: "digest", target: queue, tracker: status.NewTracker("digest")}},
+		journal:  j,
+		silencer: silencer,
+	}
+	if err := d.NotifyDetails("BTC up", "test", false, &notifier.Details{Strategy: "btc"}); err != nil {
+		t.Fatalf("queued alert failed: %v", err)
+	}
+	if entries, _ := j.Query(&journal.Filter{}); len(entries) != 0 {
+		t.Fatalf("journaled %d alerts before they were sent", len(entries))
+	}
+
+	queue.send(errors.New("smtp down"))
+	entries, err := j.Query(&journal.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(entries[0].Deliveries) != 1 {
+		t.Fatalf("journaled %+v, want the alert with its delivery", entries)
+	}
+	if delivery := entries[0].Deliveries[0]; delivery.Notifier != "digest" || delivery.Status != journal.StatusFailed {
+		t.Errorf("journaled delivery %+v, want the failure of the digest notifier", delivery)
+	}
+}
@@ -0,0 +1,38 @@
+package monitor
+
+import (
+	"context"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/escalation"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"net"
+)
+
+// buildPolicies builds the escalation policies of conf. Escalations outlive the generation,
+// so the steps notify through refs following the notifiers across reloads.
+func buildPolicies(ctx context.Context, conf *config.Escalation, server *serverSettings, notifiers map[string]notifier.Notifier, refs map[string]*notifierRef) (map[string]*escalation.Policy, error) {
+	steps := make(map[string]notifier.Notifier, len(notifiers))
+	for name := range notifiers {
+		steps[name] = refFor(refs, name)
+	}
+
+	ackURL := ""
+	if server != nil {
+		ackURL = reachableURL(server.listen)
+	}
+	return escalation.NewPolicies(conf, ackURL, steps, escalation.FromContext(ctx), templates.TextsOf(templates.LocaleFromContext(ctx)))
+}
+
+// reachableURL returns the URL of the server listening on listen for recipients of messages
+// elsewhere, empty if it listens on any or a loopback address, whose host they can't know.
+func reachableURL(listen string) string {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil || len(host) == 0 || host == "localhost" {
+		return ""
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsUnspecified() || ip.IsLoopback()) {
+		return ""
+	}
+	return "http://" + listen
+}
@@ -0,0 +1,533 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/escalation"
+	"github.com/azraeljack/crypto-monitor/journal"
+	"github.com/azraeljack/crypto-monitor/metrics"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/status"
+	"github.com/azraeljack/crypto-monitor/storage"
+	"github.com/azraeljack/crypto-monitor/strategy"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"os"
+	"sort"
+	"strings"
+)
+
+type runningCollector struct {
+	collector.Collector
+	componentType string
+	rawConf       string
+	cancel        context.CancelFunc
+
+	tracker    *status.Tracker
+	connection connection
+}
+
+type runningNotifier struct {
+	notifier.Notifier
+	componentType string
+	rawConf       string
+	cancel        context.CancelFunc
+	tracker       *status.Tracker
+	group         *groupNotifier
+}
+
+type runningStrategy struct {
+	strategy.Strategy
+	componentType string
+	rawConf       string
+	collectors    []*runningCollector
+	dispatcher    *dispatcher
+	cancel        context.CancelFunc
+	tracker       *status.Tracker
+}
+
+// generation is the set of components built from one version of the config.
+// Components whose config didn't change are carried over from the previous generation.
+type generation struct {
+	collectors map[string]*runningCollector
+	notifiers  map[string]*runningNotifier
+	strategies map[string]*runningStrategy
+
+	// notifier names in config order, and the notifier strategies use if they don't name any
+	notifierNames []string
+	router        notifier.Notifier
+	// escalation policies, which strategies and routes name like notifiers
+	escalations map[string]*escalation.Policy
+
+	server  *serverSettings
+	storage *storage.Settings
+	journal *journal.Settings
+	state   *state.Settings
+
+	// locale of the monitor's own messages, the default one of the components
+	locale string
+	texts  *templates.Texts
+}
+
+// build creates the components of conf, reusing the unchanged ones of prev which may be nil.
+// Strategies notify through refs, whose targets are switched to the new notifiers on commit.
+func build(ctx context.Context, conf *config.Config, prev *generation, refs map[string]*notifierRef, defaultRef *notifierRef) (*generation, error) {
+	if prev == nil {
+		prev = &generation{}
+	}
+
+	gen := &generation{
+		collectors: make(map[string]*runningCollector),
+		notifiers:  make(map[string]*runningNotifier),
+		strategies: make(map[string]*runningStrategy),
+	}
+	errs := config.Errors{}
+
+	gen.locale = templates.LocaleZh
+	if len(conf.Locale) > 0 {
+		if err := templates.CheckLocale(conf.Locale); err != nil {
+			errs.Add(conf.Path("locale", -1), err)
+		} else {
+			gen.locale = conf.Locale
+		}
+	}
+	gen.texts = templates.TextsOf(gen.locale)
+	ctx = templates.NewContext(ctx, gen.locale)
+
+	for i, collectorConf := range conf.Collectors {
+		path := conf.Path("collectors", i)
+		component, err := loadComponent(collectorConf)
+		if err != nil {
+			errs.Add(path, err)
+			continue
+		}
+		if _, exist := gen.collectors[component.Name]; exist {
+			errs.Addf(config.JoinPath(path, "name"), "duplicate collector name %q", component.Name)
+			continue
+		}
+
+		rawConf := canonical(collectorConf)
+		if old, exist := prev.collectors[component.Name]; exist && old.rawConf == rawConf {
+			gen.collectors[component.Name] = old
+			continue
+		}
+
+		tracker := status.NewTracker(component.Name)
+		colCtx, cancel := context.WithCancel(status.NewContext(ctx, tracker))
+		col, err := collector.GetRegistry().GetCollector(colCtx, collectorConf)
+		if err != nil {
+			cancel()
+			// keep the name known so references to it aren't reported as well
+			gen.collectors[component.Name] = nil
+			errs.Add(path, err)
+			continue
+		}
+		// the status server serves the recorded prices back on /prices
+		col = &recordingCollector{Collector: col, name: component.Name, store: storage.FromContext(ctx)}
+		gen.collectors[component.Name] = &runningCollector{
+			Collector:     col,
+			componentType: component.Type,
+			rawConf:       rawConf,
+			cancel:        cancel,
+			tracker:       tracker,
+		}
+	}
+
+	namedNotifiers := make(map[string]notifier.Notifier)
+	for i, notifierConf := range conf.Notifiers {
+		path := conf.Path("notifiers", i)
+		component, err := loadComponent(notifierConf)
+		if err != nil {
+			errs.Add(path, err)
+			continue
+		}
+		if _, exist := gen.notifiers[component.Name]; exist {
+			errs.Addf(config.JoinPath(path, "name"), "duplicate notifier name %q", component.Name)
+			continue
+		}
+		gen.notifierNames = append(gen.notifierNames, component.Name)
+
+		rawConf := fingerprint(notifierConf, component, gen.locale)
+		if old, exist := prev.notifiers[component.Name]; exist && old.rawConf == rawConf {
+			gen.notifiers[component.Name] = old
+			namedNotifiers[component.Name] = old.Notifier
+			continue
+		}
+
+		var quietHours *silence.QuietHours
+		if component.QuietHours != nil {
+			if quietHours, err = silence.ParseQuietHours(component.QuietHours); err != nil {
+				gen.notifiers[component.Name] = nil
+				errs.Add(config.JoinPath(path, "quiet_hours"), err)
+				continue
+			}
+		}
+		var tmpls *templates.Set
+		texts := gen.texts
+		if component.MessageTemplate != nil {
+			if tmpls, err = templates.Parse(component.MessageTemplate, gen.locale); err != nil {
+				gen.notifiers[component.Name] = nil
+				errs.Add(config.JoinPath(path, "message_template"), err)
+				continue
+			}
+			texts = templates.TextsOf(tmpls.Locale())
+		}
+		var grouping *groupSettings
+		if component.Grouping != nil {
+			if grouping, err = parseGroup(component.Grouping); err != nil {
+				gen.notifiers[component.Name] = nil
+				errs.Add(config.JoinPath(path, "grouping"), err)
+				continue
+			}
+		}
+
+		tracker := status.NewTracker(component.Name)
+		notCtx, cancel := context.WithCancel(status.NewContext(ctx, tracker))
+		not, err := notifier.GetRegistry().GetNotifier(notCtx, notifierConf)
+		if err != nil {
+			cancel()
+			gen.notifiers[component.Name] = nil
+			errs.Add(path, err)
+			continue
+		}
+		if tmpls != nil {
+			not = &templatedNotifier{name: component.Name, target: not, templates: tmpls}
+		}
+		if quietHours != nil {
+			not = &quietNotifier{name: component.Name, target: not, hours: quietHours, texts: texts, ctx: notCtx}
+		}
+		running := &runningNotifier{
+			Notifier:      &trackedNotifier{name: component.Name, target: not, tracker: tracker},
+			componentType: component.Type,
+			rawConf:       rawConf,
+			cancel:        cancel,
+			tracker:       tracker,
+		}
+		if grouping != nil {
+			running.group = &groupNotifier{name: component.Name, target: running.Notifier, settings: grouping, texts: texts}
+			running.Notifier = running.group
+		}
+		gen.notifiers[component.Name] = running
+		namedNotifiers[component.Name] = running.Notifier
+	}
+
+	if conf.Server != nil {
+		server, err := parseServer(conf.Server)
+		if err != nil {
+			errs.Add(conf.Path("server", -1), err)
+		}
+		gen.server = server
+	}
+
+	// strategies without explicit notifiers notify every notifier in config order, unless routing
+	// rules pick them per alert
+	routing := conf.Routing
+	if routing == nil {
+		routing = &config.Routing{}
+	}
+	routable := namedNotifiers
+	if conf.Escalation != nil {
+		policies, err := buildPolicies(ctx, conf.Escalation, gen.server, namedNotifiers, refs)
+		if err != nil {
+			errs.Add(conf.Path("escalation", -1), err)
+		}
+		gen.escalations = policies
+
+		// routes may name policies, while the default is every notifier but them
+		routable = make(map[string]notifier.Notifier, len(namedNotifiers)+len(policies))
+		for name, not := range namedNotifiers {
+			routable[name] = not
+		}
+		for name, policy := range policies {
+			routable[name] = policy
+		}
+	}
+	router, err := notifier.NewRouter(routing, gen.notifierNames, routable)
+	if err != nil {
+		errs.Add(conf.Path("routing", -1), err)
+	}
+	gen.router = router
+	if conf.Journal != nil {
+		settings, err := journal.ParseSettings(conf.Journal)
+		if err != nil {
+			errs.Add(conf.Path("journal", -1), err)
+		}
+		gen.journal = settings
+	}
+	if conf.Storage != nil {
+		settings, err := storage.ParseSettings(conf.Storage)
+		if err != nil {
+			errs.Add(conf.Path("storage", -1), err)
+		}
+		gen.storage = settings
+	}
+	if conf.State != nil {
+		settings, err := state.ParseSettings(conf.State)
+		if err != nil {
+			errs.Add(conf.Path("state", -1), err)
+		}
+		gen.state = settings
+	}
+
+	for i, strategyConf := range conf.Strategies {
+		path := conf.Path("strategies", i)
+		component, err := loadComponent(strategyConf)
+		if err != nil {
+			errs.Add(path, err)
+			continue
+		}
+		if _, exist := gen.strategies[component.Name]; exist {
+			errs.Addf(config.JoinPath(path, "name"), "duplicate strategy name %q", component.Name)
+			continue
+		}
+
+		var collectors []*runningCollector
+		if len(component.Collectors) > 0 {
+			for j, name := range component.Collectors {
+				col, exist := gen.collectors[name]
+				if !exist {
+					errs.Addf(config.Index(config.JoinPath(path, "collectors"), j), "unknown collector %q", name)
+					continue
+				}
+				collectors = append(collectors, col)
+			}
+		} else {
+			for _, collectorConf := range conf.Collectors {
+				if component, err := loadComponent(collectorConf); err == nil {
+					collectors = append(collectors, gen.collectors[component.Name])
+				}
+			}
+		}
+
+		notifiers := []notifier.Notifier{defaultRef}
+		if len(component.Notifiers) > 0 {
+			notifiers = make([]notifier.Notifier, 0, len(component.Notifiers))
+			for j, name := range component.Notifiers {
+				if !gen.hasNotifier(name) {
+					errs.Addf(config.Index(config.JoinPath(path, "notifiers"), j), "unknown notifier %q", name)
+					continue
+				}
+				notifiers = append(notifiers, refFor(refs, name))
+			}
+		}
+
+		rawConf := fingerprint(strategyConf, component, gen.locale)
+		if old, exist := prev.strategies[component.Name]; exist && old.rawConf == rawConf && sameCollectors(old.collectors, collectors) {
+			gen.strategies[component.Name] = old
+			continue
+		}
+
+		tracker := status.NewTracker(component.Name)
+		strataCtx, cancel := context.WithCancel(status.NewContext(ctx, tracker))
+		strata, err := strategy.GetRegistry().GetStrategy(strataCtx, strategyConf)
+		if err != nil {
+			cancel()
+			errs.Add(path, err)
+			continue
+		}
+		for _, col := range collectors {
+			if col != nil {
+				strata.AddCollectors(col.Collector)
+			}
+		}
+		dispatch := &dispatcher{
+			strategy: component.Name,
+			targets:  notifiers,
+			journal:  journal.FromContext(ctx),
+			silencer: silence.FromContext(ctx),
+		}
+		strata.AddNotifiers(dispatch)
+
+		gen.strategies[component.Name] = &runningStrategy{
+			Strategy:      strata,
+			componentType: component.Type,
+			rawConf:       rawConf,
+			collectors:    collectors,
+			dispatcher:    dispatch,
+			cancel:        cancel,
+			tracker:       tracker,
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		gen.discard(prev)
+		return nil, err
+	}
+	return gen, nil
+}
+
+// discard stops the components of g which aren't shared with other, which may be nil.
+func (g *generation) discard(other *generation) {
+	if other == nil {
+		other = &generation{}
+	}
+	for name, strata := range g.strategies {
+		if strata != nil && other.strategies[name] != strata {
+			strata.cancel()
+		}
+		if _, exist := other.strategies[name]; !exist {
+			metrics.ForgetStrategy(name)
+		}
+	}
+	for name, not := range g.notifiers {
+		if not != nil && other.notifiers[name] != not {
+			// the pending groups are sent before the notifier stops
+			go func(not *runningNotifier) {
+				not.group.flushAll()
+				not.cancel()
+			}(not)
+		}
+		if _, exist := other.notifiers[name]; !exist {
+			metrics.ForgetNotifier(name)
+		}
+	}
+	for name, col := range g.collectors {
+		if col != nil && other.collectors[name] != col {
+			col.cancel()
+		}
+		if _, exist := other.collectors[name]; !exist {
+			metrics.ForgetCollector(name)
+		}
+	}
+}
+
+// stop shuts the generation down: strategies are stopped first and deliver the notifications
+// they already matched, then the pending groups are sent and msg is broadcast before the
+// notifiers and collectors are stopped.
+// Waiting for the components gives up once ctx is done.
+func (g *generation) stop(ctx context.Context, msg string) error {
+	var timedOut []string
+
+	for _, strata := range g.strategies {
+		if strata != nil {
+			strata.cancel()
+		}
+	}
+	for name, strata := range g.strategies {
+		if strata != nil && strata.Wait(ctx) != nil {
+			timedOut = append(timedOut, "strategy "+name)
+		}
+	}
+
+	broadcast := make(chan struct{})
+	go func() {
+		for _, not := range g.notifiers {
+			if not != nil {
+				not.group.flushAll()
+			}
+		}
+		g.broadcast(msg)
+		close(broadcast)
+	}()
+	select {
+	case <-broadcast:
+	case <-ctx.Done():
+		timedOut = append(timedOut, "stop message")
+	}
+
+	for _, not := range g.notifiers {
+		if not != nil {
+			not.cancel()
+		}
+	}
+	for name, not := range g.notifiers {
+		if not == nil {
+			continue
+		}
+		if waiter, ok := not.Notifier.(notifier.Waiter); ok && waiter.Wait(ctx) != nil {
+			timedOut = append(timedOut, "notifier "+name)
+		}
+	}
+
+	for _, col := range g.collectors {
+		if col != nil {
+			col.cancel()
+		}
+	}
+
+	if len(timedOut) > 0 {
+		sort.Strings(timedOut)
+		return fmt.Errorf("gave up waiting for %s: %w", strings.Join(timedOut, ", "), ctx.Err())
+	}
+	return nil
+}
+
+// broadcast sends msg to every notifier, bypassing routing.
+func (g *generation) broadcast(msg string) {
+	for _, name := range g.notifierNames {
+		_ = g.notifiers[name].Notify(msg, "main", false)
+	}
+}
+
+// hasNotifier reports whether name is a notifier or an escalation policy.
+func (g *generation) hasNotifier(name string) bool {
+	if _, exist := g.notifiers[name]; exist {
+		return true
+	}
+	_, exist := g.escalations[name]
+	return exist
+}
+
+// notifier returns the notifier or escalation policy of a name, or nil if there is none.
+func (g *generation) notifier(name string) notifier.Notifier {
+	if not := g.notifiers[name]; not != nil {
+		return not.Notifier
+	}
+	if policy := g.escalations[name]; policy != nil {
+		return policy
+	}
+	return nil
+}
+
+func sameCollectors(a, b []*runningCollector) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint identifies the config of a component along with what it depends on outside of
+// it, the default locale and its template files, so a change of either rebuilds it.
+func fingerprint(rawConf json.RawMessage, component *config.Component, locale string) string {
+	parts := []string{canonical(rawConf), locale}
+	if tmpl := component.MessageTemplate; tmpl != nil {
+		for _, file := range []string{tmpl.FiringFile, tmpl.ResolvedFile} {
+			if len(file) > 0 {
+				content, _ := os.ReadFile(file)
+				parts = append(parts, string(content))
+			}
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// canonical re-encodes a component config so formatting and key order don't count as changes.
+func canonical(rawConf json.RawMessage) string {
+	var value any
+	if err := json.Unmarshal(rawConf, &value); err != nil {
+		return string(rawConf)
+	}
+	normalized, _ := json.Marshal(value)
+	return string(normalized)
+}
+
+func loadComponent(rawConf json.RawMessage) (*config.Component, error) {
+	component := &config.Component{}
+	if err := json.Unmarshal(rawConf, component); err != nil {
+		return nil, config.Errors{{Err: err}}
+	}
+	if len(component.Name) == 0 {
+		return nil, config.Errors{{Path: "name", Err: errors.New("required")}}
+	}
+	return component, nil
+}
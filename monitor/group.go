@@ -0,0 +1,247 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/templates"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var groupLabels = []string{"strategy", "direction", "exchange", "severity", "state"}
+
+type groupSettings struct {
+	by      []string
+	wait    time.Duration
+	maxSize int
+}
+
+func parseGroup(conf *config.Group) (*groupSettings, error) {
+	errs := config.Errors{}
+	settings := &groupSettings{
+		by:      conf.By,
+		wait:    config.ParseDuration(&errs, "wait", conf.Wait, 30*time.Second),
+		maxSize: conf.MaxSize,
+	}
+	if len(settings.by) == 0 {
+		settings.by = []string{"strategy", "direction", "exchange"}
+	}
+	for i, label := range settings.by {
+		if !contains(groupLabels, label) {
+			errs.Addf(config.Index("by", i), "unknown label %q, expecting one of %v", label, groupLabels)
+		}
+	}
+	if settings.wait == 0 {
+		errs.Addf("wait", "must be positive")
+	}
+	if settings.maxSize == 0 {
+		settings.maxSize = 50
+	} else if settings.maxSize < 0 {
+		errs.Addf("max_size", "must be positive")
+	}
+	return settings, errs.Err()
+}
+
+type groupedAlert struct {
+	msg      string
+	from     string
+	throttle bool
+	details  *notifier.Details
+}
+
+type alertGroup struct {
+	key    string
+	alerts []*groupedAlert
+	timer  *time.Timer
+}
+
+// groupNotifier batches the alerts a notifier receives by group key, and sends each group
+// as one message once its wait is over or it's full. Messages about no price aren't grouped.
+type groupNotifier struct {
+	name     string
+	target   notifier.Notifier
+	settings *groupSettings
+	texts    *templates.Texts
+
+	mutex  sync.Mutex
+	groups map[string]*alertGroup
+}
+
+func (g *groupNotifier) Notify(msg, from string, throttle bool) error {
+	return g.target.Notify(msg, from, throttle)
+}
+
+// NotifyDetails queues the alert in its group, the group is sent later unless it's full.
+func (g *groupNotifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	if details == nil || details.Price == nil {
+		return notifier.Send(g.target, msg, from, throttle, details)
+	}
+
+	key := g.key(details)
+	g.mutex.Lock()
+	if g.groups == nil {
+		g.groups = make(map[string]*alertGroup)
+	}
+	group, exist := g.groups[key]
+	if !exist {
+		group = &alertGroup{key: key}
+		group.timer = time.AfterFunc(g.settings.wait, func() { g.flush(group) })
+		g.groups[key] = group
+	}
+	group.alerts = append(group.alerts, &groupedAlert{msg: msg, from: from, throttle: throttle, details: details})
+	full := len(group.alerts) >= g.settings.maxSize
+	if full {
+		group.timer.Stop()
+		delete(g.groups, key)
+	}
+	g.mutex.Unlock()
+
+	details.Report(g.name, notifier.ErrGrouped)
+	if full {
+		return g.send(group)
+	}
+	log.Debugf("notifier %s grouped alert from %s in %s", g.name, from, key)
+	return nil
+}
+
+func (g *groupNotifier) Wait(ctx context.Context) error {
+	if waiter, ok := g.target.(notifier.Waiter); ok {
+		return waiter.Wait(ctx)
+	}
+	return nil
+}
+
+// key groups alerts by the labels of the settings, and always by state so resolved alerts
+// aren't listed along with firing ones.
+func (g *groupNotifier) key(details *notifier.Details) string {
+	values := make([]string, 0, len(g.settings.by)+1)
+	for _, label := range g.settings.by {
+		value := ""
+		switch label {
+		case "strategy":
+			value = details.Strategy
+		case "direction":
+			value = directionOf(details)
+		case "exchange":
+			value = details.Price.Exchange
+		case "severity":
+			value = details.Severity.String()
+		case "state":
+			value = details.State
+		}
+		values = append(values, label+"="+value)
+	}
+	if !contains(g.settings.by, "state") {
+		values = append(values, "state="+details.State)
+	}
+	return strings.Join(values, ",")
+}
+
+func directionOf(details *notifier.Details) string {
+	if details.Price.Rising() {
+		return "up"
+	}
+	return "down"
+}
+
+// flush sends a group once its wait is over, unless it was sent already.
+func (g *groupNotifier) flush(group *alertGroup) {
+	g.mutex.Lock()
+	if g.groups[group.key] != group {
+		g.mutex.Unlock()
+		return
+	}
+	delete(g.groups, group.key)
+	g.mutex.Unlock()
+
+	_ = g.send(group)
+}
+
+// flushAll sends every pending group right away, e.g. before the notifier stops.
+func (g *groupNotifier) flushAll() {
+	if g == nil {
+		return
+	}
+
+	g.mutex.Lock()
+	groups := make([]*alertGroup, 0, len(g.groups))
+	for key, group := range g.groups {
+		group.timer.Stop()
+		delete(g.groups, key)
+		groups = append(groups, group)
+	}
+	g.mutex.Unlock()
+
+	for _, group := range groups {
+		_ = g.send(group)
+	}
+}
+
+// send delivers a group of a single alert as is, or else one message listing the pairs of
+// the group by the size of their move, throttled by the group key. The outcome is reported
+// to every alert of the group.
+func (g *groupNotifier) send(group *alertGroup) error {
+	var err error
+	if len(group.alerts) == 1 {
+		alert := group.alerts[0]
+		err = notifier.Send(g.target, alert.msg, alert.from, alert.throttle, alert.details.WithReporter(nil))
+	} else {
+		msg, details := g.combine(group)
+		log.Infof("notifier %s sending %d grouped alerts of %s", g.name, len(group.alerts), group.key)
+		err = notifier.Send(g.target, msg, "Group^"+group.key, true, details)
+	}
+
+	for _, alert := range group.alerts {
+		alert.details.Report(g.name, err)
+	}
+	return g.report(err)
+}
+
+// combine returns the message listing the alerts of a group and its details.
+func (g *groupNotifier) combine(group *alertGroup) (string, *notifier.Details) {
+	sort.SliceStable(group.alerts, func(i, j int) bool {
+		return math.Abs(group.alerts[i].details.Price.RelativePriceChange) > math.Abs(group.alerts[j].details.Price.RelativePriceChange)
+	})
+
+	first := group.alerts[0].details
+	details := &notifier.Details{Strategy: first.Strategy, Severity: first.Severity, State: first.State}
+	builder := &strings.Builder{}
+	if details.State == notifier.StateResolved {
+		fmt.Fprintf(builder, g.texts.GroupResolved, len(group.alerts))
+	} else {
+		fmt.Fprintf(builder, g.texts.GroupFiring, len(group.alerts))
+	}
+	for _, alert := range group.alerts {
+		fmt.Fprintf(builder, g.texts.GroupLine, alert.details.Headline(), alert.details.Price.ClosePrice)
+		if alert.details.Strategy != details.Strategy {
+			details.Strategy = ""
+		}
+		if alert.details.Severity > details.Severity {
+			details.Severity = alert.details.Severity
+		}
+	}
+	return builder.String(), details
+}
+
+func (g *groupNotifier) report(err error) error {
+	if err != nil && !errors.Is(err, notifier.ErrThrottled) && !errors.Is(err, notifier.ErrSilenced) {
+		log.Warnf("notifier %s failed to send grouped alerts: %v", g.name, err)
+	}
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,90 +3,311 @@ package monitor
 import (
 	"context"
 	"encoding/json"
-	"github.com/azraeljack/crypto-monitor/collector"
+	"errors"
+	"fmt"
 	"github.com/azraeljack/crypto-monitor/config"
-	"github.com/azraeljack/crypto-monitor/notifier"
-	"github.com/azraeljack/crypto-monitor/strategy"
+	"github.com/azraeljack/crypto-monitor/escalation"
+	"github.com/azraeljack/crypto-monitor/journal"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/storage"
 	log "github.com/sirupsen/logrus"
 	"io"
-	"os"
-	"path"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Monitor struct {
-	collectors []collector.Collector
-	notifiers  []notifier.Notifier
-	strategies []strategy.Strategy
+	configFile string
+
+	mutex   sync.Mutex
+	current *generation
+	running bool
+	stopped bool
+
+	// files are watched for changes, the config file along with the ones it includes
+	files []string
+
+	startedAt time.Time
+	loadedAt  time.Time
+
+	// the status server, started along with the monitor if configured
+	server        *http.Server
+	serverListen  string
+	serverWorkers sync.WaitGroup
+	stopServer    context.CancelFunc
+	recheck       chan struct{}
+
+	// store records prices, journal alerts and state persists dedupe and throttle windows if
+	// configured, components get them from their context
+	store   *storage.Store
+	journal *journal.Journal
+	state   *state.Store
+
+	// silencer holds alerts back while silenced, silences are kept in the state store
+	silencer *silence.Silencer
+	// escalations notifies the next steps of unacknowledged alerts across reloads
+	escalations *escalation.Scheduler
+
+	// strategies notify through these refs, which follow the notifiers across reloads
+	notifierRefs map[string]*notifierRef
+	defaultRef   *notifierRef
 
 	ctx context.Context
 }
 
-func NewMonitor(ctx context.Context, configFile string) *Monitor {
-	if strings.HasPrefix(configFile, ".") {
-		cwd, _ := os.Getwd()
-		configFile = path.Join(cwd, configFile)
+func NewMonitor(ctx context.Context, configFile string) (*Monitor, error) {
+	monitor := &Monitor{
+		configFile:   configFile,
+		files:        []string{configFile},
+		notifierRefs: make(map[string]*notifierRef),
+		defaultRef:   &notifierRef{},
+		recheck:      make(chan struct{}, 1),
+		store:        storage.New(),
+		journal:      journal.New(),
+		state:        state.New(),
+		escalations:  escalation.NewScheduler(),
 	}
+	monitor.silencer = silence.NewSilencer(monitor.state.Cache("silences"), monitor.sendSummary)
 
-	log.Infof("loading config file: %s ...", configFile)
-	f, err := os.Open(configFile)
+	ctx = storage.NewContext(ctx, monitor.store)
+	ctx = journal.NewContext(ctx, monitor.journal)
+	ctx = state.NewContext(ctx, monitor.state)
+	ctx = escalation.NewContext(ctx, monitor.escalations)
+	monitor.ctx = silence.NewContext(ctx, monitor.silencer)
+
+	gen, err := monitor.load(nil)
 	if err != nil {
-		log.Panicf("failed to load config: %v", err)
+		return nil, err
 	}
+	monitor.commit(gen)
 
-	rawConf, err := io.ReadAll(f)
-	if err != nil {
-		log.Panicf("failed to read config: %v", err)
+	return monitor, nil
+}
+
+// Validate builds every component of the config without starting anything and
+// returns all problems found.
+func Validate(configFile string) error {
+	// components tie their background work to the context, a cancelled one keeps them idle
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewMonitor(ctx, configFile)
+	return err
+}
+
+// QueryAlerts reads the alerts matching filter from the journal of the config file,
+// without building any component.
+func QueryAlerts(configFile string, filter *journal.Filter) ([]*journal.Entry, error) {
+	conf, err := config.Load(configFile)
+	if conf == nil {
+		return nil, err
+	}
+	if conf.Journal == nil || len(conf.Journal.Path) == 0 {
+		return nil, errors.New("no alert journal configured")
 	}
+	return journal.Query(conf.Journal.Path, filter)
+}
 
-	conf := &config.Config{}
-	if err := json.Unmarshal(rawConf, conf); err != nil {
-		log.Panicf("failed to parse config: %v", err)
+// Snooze silences the alerts of the running monitor matching strategy and pair, empty to match
+// any, for duration through the status server of the config file.
+func Snooze(configFile, strategy, pair string, duration time.Duration) (*silence.Silence, error) {
+	conf, err := config.Load(configFile)
+	if conf == nil {
+		return nil, err
+	}
+	if conf.Server == nil || len(conf.Server.Listen) == 0 {
+		return nil, errors.New("no status server configured")
 	}
 
-	monitor := &Monitor{
-		ctx: ctx,
+	query := url.Values{"duration": {duration.String()}}
+	if len(strategy) > 0 {
+		query.Set("strategy", strategy)
+	}
+	if len(pair) > 0 {
+		query.Set("pair", pair)
+	}
+	endpoint := fmt.Sprintf("%s/silences?%s", serverURL(conf.Server.Listen), query.Encode())
+
+	request, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Server.Token) > 0 {
+		request.Header.Set("authorization", "Bearer "+conf.Server.Token)
 	}
 
-	for _, collectorConf := range conf.Collectors {
-		monitor.collectors = append(monitor.collectors, collector.GetRegistry().GetCollector(ctx, collectorConf))
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	for _, notifierConf := range conf.Notifiers {
-		monitor.notifiers = append(monitor.notifiers, notifier.GetRegistry().GetNotifier(ctx, notifierConf))
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	created := &silence.Silence{}
+	if err := json.Unmarshal(body, created); err != nil {
+		return nil, err
 	}
+	return created, nil
+}
 
-	for _, strategyConf := range conf.Strategies {
-		strata := strategy.GetRegistry().GetStrategy(ctx, strategyConf)
-		strata.AddNotifiers(monitor.notifiers...)
-		strata.AddCollectors(monitor.collectors...)
+// load reads the config file and builds its components on top of prev.
+func (m *Monitor) load(prev *generation) (*generation, error) {
+	log.Infof("loading config file: %s ...", m.configFile)
+	conf, err := config.Load(m.configFile)
+	if conf == nil {
+		return nil, err
+	}
+	// watch the files of an invalid config too, so fixing any of them triggers a reload
+	m.files = conf.Files
 
-		monitor.strategies = append(monitor.strategies, strata)
+	gen, buildErr := build(m.ctx, conf, prev, m.notifierRefs, m.defaultRef)
+	if err != nil {
+		// the config has top level problems, the components were only built to report theirs too
+		if gen != nil {
+			gen.discard(prev)
+		}
+		errs := config.Errors{}
+		errs.Add("", err)
+		errs.Add("", buildErr)
+		return nil, errs
 	}
 
-	return monitor
+	return gen, buildErr
 }
 
-func (m *Monitor) Start() {
-	for _, strata := range m.strategies {
-		strata.Run()
+// commit makes gen the running generation, stopping components of the previous one
+// which gen doesn't reuse and starting new strategies if the monitor is running.
+func (m *Monitor) commit(gen *generation) {
+	prev := m.current
+	if prev != nil {
+		prev.discard(gen)
 	}
-	go func() {
-		for _, not := range m.notifiers {
-			not.Notify("监控程序已启动", "main", false)
+
+	for name, ref := range m.notifierRefs {
+		if not := gen.notifier(name); not != nil {
+			ref.set(not)
+		} else {
+			delete(m.notifierRefs, name)
 		}
-	}()
+	}
+	m.defaultRef.set(gen.router)
+	m.current = gen
+	m.loadedAt = time.Now()
+
+	// test the connections of new collectors right away
+	select {
+	case m.recheck <- struct{}{}:
+	default:
+	}
+
+	if m.running {
+		for name, strata := range gen.strategies {
+			if prev == nil || prev.strategies[name] != strata {
+				strata.Run()
+			}
+		}
+	}
 }
 
-func (m *Monitor) Stop() <-chan struct{} {
-	stopCh := make(chan struct{}, 1)
+// Start runs the strategies and announces the start to every notifier.
+func (m *Monitor) Start() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stopped {
+		return errors.New("monitor already stopped")
+	}
+	if m.running {
+		return errors.New("monitor already started")
+	}
 
-	go func() {
-		for _, not := range m.notifiers {
-			not.Notify("监控程序已停止", "main", false)
+	if settings := m.current.storage; settings != nil {
+		if err := m.store.Open(settings); err != nil {
+			return err
+		}
+	}
+	if settings := m.current.journal; settings != nil {
+		if err := m.journal.Open(settings); err != nil {
+			_ = m.store.Close()
+			return err
+		}
+	}
+	if settings := m.current.state; settings != nil {
+		if err := m.state.Open(settings); err != nil {
+			_ = m.store.Close()
+			_ = m.journal.Close()
+			return err
 		}
-		close(stopCh)
-	}()
+	}
 
-	return stopCh
+	m.running = true
+	m.startedAt = time.Now()
+	for _, strata := range m.current.strategies {
+		strata.Run()
+	}
+	if m.current.server != nil {
+		m.serve(m.current.server)
+	}
+
+	go m.current.broadcast(m.current.texts.Started)
+	return nil
+}
+
+// Stop shuts the monitor down, waiting until ctx is done for pending notifications
+// to be delivered and the components to exit. The monitor can't be restarted.
+func (m *Monitor) Stop(ctx context.Context) error {
+	m.mutex.Lock()
+	if m.stopped {
+		m.mutex.Unlock()
+		return errors.New("monitor already stopped")
+	}
+	m.running = false
+	m.stopped = true
+	gen := m.current
+	m.mutex.Unlock()
+
+	log.Info("stopping the monitor...")
+	m.silencer.Stop()
+	m.escalations.Stop()
+	err := gen.stop(ctx, gen.texts.Stopped)
+	if serverErr := m.shutdown(ctx); serverErr != nil && err == nil {
+		err = fmt.Errorf("failed to shutdown the status server: %w", serverErr)
+	}
+	if storeErr := m.store.Close(); storeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close the price storage: %w", storeErr)
+	}
+	if journalErr := m.journal.Close(); journalErr != nil && err == nil {
+		err = fmt.Errorf("failed to close the alert journal: %w", journalErr)
+	}
+	if stateErr := m.state.Close(); stateErr != nil && err == nil {
+		err = fmt.Errorf("failed to close the state store: %w", stateErr)
+	}
+	return err
+}
+
+// sendSummary sends the summary of the alerts of a strategy held back by a silence to the
+// strategy's notifiers.
+func (m *Monitor) sendSummary(strategy string, summary *silence.Summary) {
+	m.mutex.Lock()
+	strata := m.current.strategies[strategy]
+	texts := m.current.texts
+	running := m.running
+	m.mutex.Unlock()
+
+	if !running {
+		return
+	}
+	if strata == nil {
+		log.Warnf("strategy %s is gone, dropping the summary of its silenced alerts", strategy)
+		return
+	}
+	strata.dispatcher.broadcast(summary.Message(texts, texts.Silenced), "Silence^"+strategy)
 }
@@ -0,0 +1,208 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	_ "github.com/azraeljack/crypto-monitor/strategy/price_change"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCollector sends a price moving by 5% every few milliseconds.
+type fakeCollector struct {
+	ctx context.Context
+}
+
+func (c *fakeCollector) CollectAvgPrice(ctx context.Context, symbol1, symbol2 string) <-chan float64 {
+	ch := make(chan float64)
+	close(ch)
+	return ch
+}
+
+func (c *fakeCollector) CollectWindowPrice(ctx context.Context, symbol1, symbol2 string, window time.Duration) <-chan *collector.WindowPrice {
+	ch := make(chan *collector.WindowPrice)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			price := &collector.WindowPrice{
+				Symbol1:             symbol1,
+				Symbol2:             symbol2,
+				OpenPrice:           100,
+				ClosePrice:          105,
+				AbsolutePriceChange: 5,
+				RelativePriceChange: 5,
+				Exchange:            "fake",
+			}
+			select {
+			case ch <- price:
+			case <-ctx.Done():
+				return
+			case <-c.ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (c *fakeCollector) Type() string {
+	return "fake"
+}
+
+func (c *fakeCollector) TestConnection() bool {
+	return true
+}
+
+// fakeNotifier counts the messages it receives.
+type fakeNotifier struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+var notified = &fakeNotifier{}
+
+func (n *fakeNotifier) Notify(msg, from string, throttle bool) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+func (n *fakeNotifier) count() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return len(n.messages)
+}
+
+func init() {
+	collector.GetRegistry().Register("fake", func(ctx context.Context, rawConf json.RawMessage) (collector.Collector, error) {
+		return &fakeCollector{ctx: ctx}, nil
+	})
+	notifier.GetRegistry().Register("fake", func(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+		return notified, nil
+	})
+}
+
+const testConfig = `{
+	"collectors": [{"type": "fake", "name": "fake"}],
+	"notifiers": [{"type": "fake", "name": "fake"}],
+	"strategies": [{"type": "price_change", "name": "btc", "symbol1": "BTC", "symbol2": "USDT", "percentage": %s, "window_size": "1s"}]
+}`
+
+func writeConfig(t *testing.T, file, percentage string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(fmt.Sprintf(testConfig, percentage)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMonitorStopsEveryGoroutine(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, file, "1")
+	m, err := NewMonitor(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "alerts", func() bool { return notified.count() >= 2 })
+
+	writeConfig(t, file, "2")
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "goroutines to exit", func() bool { return runtime.NumGoroutine() <= baseline })
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Fatalf("timed out waiting for %s, goroutines:\n%s", what, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTemplateFilesAreResolvedAndWatched(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "strategies"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	template := filepath.Join(dir, "strategies", "firing.tmpl")
+	write := func(file, content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(template, "{{.Symbol1}} moved")
+	write(filepath.Join(dir, "strategies", "btc.json"), `{"strategies": [{"type": "price_change", "name": "btc", "symbol1": "BTC", "symbol2": "USDT", "percentage": 1, "message_template": {"firing_file": "firing.tmpl"}}]}`)
+	file := filepath.Join(dir, "config.json")
+	write(file, `{
+	"locale": "en",
+	"collectors": [{"type": "fake", "name": "fake"}],
+	"notifiers": [{"type": "fake", "name": "fake"}],
+	"include": ["strategies/btc.json"]
+}`)
+
+	m, err := NewMonitor(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.current.discard(nil)
+	if m.current.texts.Started != "Monitor started" {
+		t.Errorf("started message %q, want the English one", m.current.texts.Started)
+	}
+	watched := false
+	for _, f := range m.files {
+		watched = watched || f == template
+	}
+	if !watched {
+		t.Errorf("template file %s isn't watched in %v", template, m.files)
+	}
+
+	strata := m.current.strategies["btc"]
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if m.current.strategies["btc"] != strata {
+		t.Error("strategy rebuilt though nothing changed")
+	}
+	write(template, "{{.Symbol1}} changed")
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if m.current.strategies["btc"] == strata {
+		t.Error("strategy kept though its template changed")
+	}
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"github.com/azraeljack/crypto-monitor/templates"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// quietNotifier holds the messages of a notifier back during its quiet hours, and sends a
+// summary of them once the quiet hours end.
+type quietNotifier struct {
+	name   string
+	target notifier.Notifier
+	hours  *silence.QuietHours
+	texts  *templates.Texts
+	ctx    context.Context
+
+	mutex   sync.Mutex
+	summary *silence.Summary
+}
+
+func (q *quietNotifier) Notify(msg, from string, throttle bool) error {
+	if q.hold(from, nil) {
+		return notifier.ErrSilenced
+	}
+	return q.target.Notify(msg, from, throttle)
+}
+
+func (q *quietNotifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	if q.hold(from, details) {
+		return notifier.ErrSilenced
+	}
+	return notifier.Send(q.target, msg, from, throttle, details)
+}
+
+func (q *quietNotifier) Wait(ctx context.Context) error {
+	if waiter, ok := q.target.(notifier.Waiter); ok {
+		return waiter.Wait(ctx)
+	}
+	return nil
+}
+
+// hold reports whether it's quiet hours, counting the message in the summary if so.
+func (q *quietNotifier) hold(from string, details *notifier.Details) bool {
+	now := time.Now()
+	if !q.hours.Active(now) {
+		return false
+	}
+
+	label := from
+	if details != nil && details.Price != nil {
+		label = details.Price.SymbolPair()
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.summary == nil {
+		q.summary = silence.NewSummary()
+		time.AfterFunc(q.hours.End(now).Sub(now), q.flush)
+	}
+	q.summary.Add(label)
+	log.Infof("notifier %s holds message from %s back during quiet hours %s", q.name, from, q.hours)
+	return true
+}
+
+func (q *quietNotifier) flush() {
+	q.mutex.Lock()
+	summary := q.summary
+	q.summary = nil
+	q.mutex.Unlock()
+
+	if q.ctx.Err() != nil {
+		return
+	}
+	if err := q.target.Notify(summary.Message(q.texts, q.texts.QuietHours), "QuietHours^"+q.name, false); err != nil && !errors.Is(err, notifier.ErrGrouped) {
+		log.Warnf("notifier %s failed to send the quiet hours summary: %v", q.name, err)
+	}
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"context"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/storage"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// recordingCollector records the prices a collector fetches in the price storage.
+type recordingCollector struct {
+	collector.Collector
+	name  string
+	store *storage.Store
+}
+
+func (r *recordingCollector) CollectAvgPrice(ctx context.Context, symbol1, symbol2 string) <-chan float64 {
+	prices := r.Collector.CollectAvgPrice(ctx, symbol1, symbol2)
+	resultCh := make(chan float64, cap(prices))
+
+	go func() {
+		defer close(resultCh)
+		for price := range prices {
+			r.record(symbol1+"-"+symbol2, time.Now(), price)
+			resultCh <- price
+		}
+	}()
+	return resultCh
+}
+
+func (r *recordingCollector) CollectWindowPrice(ctx context.Context, symbol1, symbol2 string, window time.Duration) <-chan *collector.WindowPrice {
+	prices := r.Collector.CollectWindowPrice(ctx, symbol1, symbol2, window)
+	resultCh := make(chan *collector.WindowPrice, cap(prices))
+
+	go func() {
+		defer close(resultCh)
+		for price := range prices {
+			r.recordWindow(price)
+			resultCh <- price
+		}
+	}()
+	return resultCh
+}
+
+// recordWindow records the open, high, low and close of a window price as a kline spanning
+// the window.
+func (r *recordingCollector) recordWindow(price *collector.WindowPrice) {
+	closeTime := time.Now()
+	if price.CloseTime > 0 {
+		closeTime = time.UnixMilli(int64(price.CloseTime))
+	}
+	openTime := closeTime
+	if price.OpenTime > 0 && price.OpenTime < price.CloseTime {
+		openTime = time.UnixMilli(int64(price.OpenTime))
+	}
+
+	k := &storage.Kline{
+		OpenTime:   openTime,
+		CloseTime:  closeTime,
+		Open:       price.OpenPrice,
+		High:       price.HighPrice,
+		Low:        price.LowPrice,
+		Close:      price.ClosePrice,
+		Samples:    1,
+		Resolution: closeTime.Sub(openTime),
+	}
+	if err := r.store.RecordKline(r.name, price.SymbolPair(), k); err != nil {
+		log.Warnf("failed to record price of %s from collector %s: %v", price.SymbolPair(), r.name, err)
+	}
+}
+
+func (r *recordingCollector) record(pair string, t time.Time, price float64) {
+	if err := r.store.Record(r.name, pair, t, price); err != nil {
+		log.Warnf("failed to record price of %s from collector %s: %v", pair, r.name, err)
+	}
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"sync"
+)
+
+// notifierRef forwards to the current notifier behind a name, so strategies keep
+// their notifiers across reloads replacing them.
+type notifierRef struct {
+	mutex  sync.RWMutex
+	target notifier.Notifier
+}
+
+func (r *notifierRef) set(target notifier.Notifier) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.target = target
+}
+
+func (r *notifierRef) get() notifier.Notifier {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.target
+}
+
+func (r *notifierRef) Notify(msg, from string, throttle bool) error {
+	if target := r.get(); target != nil {
+		return target.Notify(msg, from, throttle)
+	}
+	return nil
+}
+
+func (r *notifierRef) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	if target := r.get(); target != nil {
+		return notifier.Send(target, msg, from, throttle, details)
+	}
+	return nil
+}
+
+// refFor returns the ref of a name, adding it to refs if missing.
+func refFor(refs map[string]*notifierRef, name string) *notifierRef {
+	ref, exist := refs[name]
+	if !exist {
+		ref = &notifierRef{}
+		refs[name] = ref
+	}
+	return ref
+}
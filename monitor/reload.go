@@ -0,0 +1,172 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/journal"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/storage"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reload re-reads the config file and applies the difference to the running monitor:
+// only added or changed components are started and only removed or changed ones stopped.
+// The running config is kept if the new one is invalid.
+func (m *Monitor) Reload() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stopped {
+		return errors.New("monitor already stopped")
+	}
+
+	gen, err := m.load(m.current)
+	if err != nil {
+		log.Errorf("failed to reload config, keep running the previous one:\n%v", err)
+		return err
+	}
+
+	m.logChanges(gen)
+	m.commit(gen)
+	m.reconfigureStorage(gen.storage)
+	m.reconfigureJournal(gen.journal)
+	m.reconfigureState(gen.state)
+	if m.server == nil && m.running && gen.server != nil {
+		m.serve(gen.server)
+	} else if m.server != nil && (gen.server == nil || gen.server.listen != m.serverListen) {
+		log.Warnf("the status server keeps serving on %s until restarted", m.serverListen)
+	}
+	log.Info("config reloaded")
+
+	return nil
+}
+
+// Watch reloads the config whenever the config file or one of the files it includes changes,
+// checking every interval until the monitor's context is done.
+func (m *Monitor) Watch(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastModified := m.modTimes()
+		for {
+			select {
+			case <-ticker.C:
+				modified := m.modTimes()
+				if modified == lastModified {
+					continue
+				}
+
+				log.Infof("config file %s changed, reloading...", m.configFile)
+				_ = m.Reload()
+				// the reload may have changed the files to watch
+				lastModified = m.modTimes()
+			case <-m.ctx.Done():
+				log.Info("config watcher exited")
+				return
+			}
+		}
+	}()
+}
+
+// reconfigureStorage opens, updates or closes the price storage after a reload.
+func (m *Monitor) reconfigureStorage(settings *storage.Settings) {
+	switch {
+	case !m.running:
+	case settings == nil:
+		if err := m.store.Close(); err != nil {
+			log.Errorf("failed to close the price storage: %v", err)
+		}
+	case len(m.store.Path()) == 0:
+		if err := m.store.Open(settings); err != nil {
+			log.Errorf("failed to open the price storage: %v", err)
+		}
+	default:
+		m.store.Configure(settings)
+	}
+}
+
+// reconfigureJournal opens, moves, updates or closes the alert journal after a reload.
+func (m *Monitor) reconfigureJournal(settings *journal.Settings) {
+	if !m.running {
+		return
+	}
+	if settings != nil && settings.Path == m.journal.Path() {
+		m.journal.Configure(settings)
+		return
+	}
+	if err := m.journal.Close(); err != nil {
+		log.Errorf("failed to close the alert journal: %v", err)
+	}
+	if settings == nil {
+		return
+	}
+	if err := m.journal.Open(settings); err != nil {
+		log.Errorf("failed to open the alert journal: %v", err)
+	}
+}
+
+// reconfigureState reopens the state store after a reload if its backend or path changed,
+// carrying the unexpired windows over to the new one.
+func (m *Monitor) reconfigureState(settings *state.Settings) {
+	current := m.state.Settings()
+	if !m.running || (settings == nil && current == nil) ||
+		(settings != nil && current != nil && *settings == *current) {
+		return
+	}
+	if err := m.state.Close(); err != nil {
+		log.Errorf("failed to close the state store: %v", err)
+	}
+	if settings == nil {
+		return
+	}
+	if err := m.state.Open(settings); err != nil {
+		log.Errorf("failed to open the state store: %v", err)
+	}
+}
+
+func (m *Monitor) logChanges(gen *generation) {
+	logChanges("collector", m.current.collectors, gen.collectors)
+	logChanges("notifier", m.current.notifiers, gen.notifiers)
+	logChanges("strategy", m.current.strategies, gen.strategies)
+}
+
+func logChanges[T comparable](kind string, prev, next map[string]T) {
+	for name, component := range next {
+		old, existed := prev[name]
+		if !existed {
+			log.Infof("%s %s added", kind, name)
+		} else if old != component {
+			log.Infof("%s %s changed", kind, name)
+		}
+	}
+	for name := range prev {
+		if _, exist := next[name]; !exist {
+			log.Infof("%s %s removed", kind, name)
+		}
+	}
+}
+
+// modTimes summarizes the modification times of the watched files.
+func (m *Monitor) modTimes() string {
+	m.mutex.Lock()
+	files := m.files
+	m.mutex.Unlock()
+
+	var times strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&times, "%s:%d\n", file, modTime(file).UnixNano())
+	}
+	return times.String()
+}
+
+func modTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,542 @@
+package monitor
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/escalation"
+	"github.com/azraeljack/crypto-monitor/journal"
+	"github.com/azraeljack/crypto-monitor/metrics"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"github.com/azraeljack/crypto-monitor/status"
+	"github.com/azraeljack/crypto-monitor/storage"
+	log "github.com/sirupsen/logrus"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type serverSettings struct {
+	listen        string
+	readyMaxAge   time.Duration
+	checkInterval time.Duration
+	token         string
+}
+
+func parseServer(conf *config.Server) (*serverSettings, error) {
+	errs := config.Errors{}
+	config.Require(&errs, "listen", conf.Listen)
+	settings := &serverSettings{
+		listen:        conf.Listen,
+		readyMaxAge:   config.ParseDuration(&errs, "ready_max_age", conf.ReadyMaxAge, 5*time.Minute),
+		checkInterval: config.ParseDuration(&errs, "check_interval", conf.CheckInterval, time.Minute),
+		token:         conf.Token,
+	}
+	if settings.checkInterval == 0 {
+		errs.Addf("check_interval", "must be positive")
+	}
+	return settings, errs.Err()
+}
+
+// serverURL returns the URL of the server listening on listen, from the same host.
+func serverURL(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "http://" + listen
+	}
+	if len(host) == 0 || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// connection is the result of the last connection test of a collector.
+type connection struct {
+	mutex     sync.Mutex
+	err       error
+	checkedAt time.Time
+}
+
+func (c *connection) set(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.err = err
+	c.checkedAt = time.Now()
+}
+
+func (c *connection) get() (time.Time, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.checkedAt, c.err
+}
+
+// serve starts the HTTP server and the collector connection checks, the caller holds the mutex.
+func (m *Monitor) serve(settings *serverSettings) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", m.handleHealth)
+	mux.HandleFunc("/readyz", m.handleReady)
+	mux.HandleFunc("/status", m.handleStatus)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/alerts", m.handleAlerts)
+	mux.HandleFunc("/prices", m.handlePrices)
+	mux.HandleFunc("/silences", m.handleSilences)
+	mux.HandleFunc("/ack", m.handleAck)
+	mux.HandleFunc("/escalations", m.handleEscalations)
+
+	m.server = &http.Server{Addr: settings.listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	m.serverListen = settings.listen
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.stopServer = cancel
+
+	m.serverWorkers.Add(2)
+	go func() {
+		defer m.serverWorkers.Done()
+
+		log.Infof("serving status on %s", settings.listen)
+		if err := m.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("status server fail: %v", err)
+		}
+	}()
+	go func() {
+		defer m.serverWorkers.Done()
+		m.checkConnections(ctx)
+	}()
+}
+
+// shutdown stops the HTTP server and the connection checks.
+func (m *Monitor) shutdown(ctx context.Context) error {
+	if m.server == nil {
+		return nil
+	}
+
+	m.stopServer()
+	err := m.server.Shutdown(ctx)
+	m.serverWorkers.Wait()
+	return err
+}
+
+// checkConnections tests the connection of every collector each check interval, and right
+// away after a reload.
+func (m *Monitor) checkConnections(ctx context.Context) {
+	for {
+		m.mutex.Lock()
+		gen := m.current
+		m.mutex.Unlock()
+
+		for name, col := range gen.collectors {
+			var err error
+			if !col.TestConnection() {
+				err = errors.New("connection test failed")
+				log.Warnf("collector %s failed the connection test", name)
+			}
+			col.connection.set(err)
+		}
+
+		interval := time.Minute
+		if gen.server != nil {
+			interval = gen.server.checkInterval
+		}
+		select {
+		case <-time.After(interval):
+		case <-m.recheck:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Monitor) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	m.mutex.Lock()
+	stopped := m.stopped
+	m.mutex.Unlock()
+
+	if stopped {
+		http.Error(w, "stopped", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "ok")
+}
+
+func (m *Monitor) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if problems := m.readiness(); len(problems) > 0 {
+		http.Error(w, strings.Join(problems, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "ok")
+}
+
+// readiness lists the reasons the monitor isn't ready: every collector must have passed
+// its connection test, and the ones used by strategies must have fetched prices recently.
+func (m *Monitor) readiness() []string {
+	m.mutex.Lock()
+	running := m.running
+	gen := m.current
+	m.mutex.Unlock()
+
+	if !running {
+		return []string{"monitor not running"}
+	}
+
+	maxAge := 5 * time.Minute
+	if gen.server != nil {
+		maxAge = gen.server.readyMaxAge
+	}
+
+	used := make(map[*runningCollector]bool)
+	for _, strata := range gen.strategies {
+		for _, col := range strata.collectors {
+			used[col] = true
+		}
+	}
+
+	var problems []string
+	for _, name := range sortedNames(gen.collectors) {
+		col := gen.collectors[name]
+		if checkedAt, err := col.connection.get(); checkedAt.IsZero() {
+			problems = append(problems, fmt.Sprintf("collector %s: connection not tested yet", name))
+		} else if err != nil {
+			problems = append(problems, fmt.Sprintf("collector %s: %v", name, err))
+		}
+
+		if !used[col] {
+			continue
+		}
+		if lastActivity := col.tracker.LastActivity(); lastActivity.IsZero() {
+			problems = append(problems, fmt.Sprintf("collector %s: no price fetched yet", name))
+		} else if age := time.Since(lastActivity); age > maxAge {
+			problems = append(problems, fmt.Sprintf("collector %s: no price fetched for %v", name, age.Truncate(time.Second)))
+		}
+	}
+	return problems
+}
+
+type componentStatus struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	*status.Snapshot
+
+	// Connected and CheckedAt are the last connection test of a collector
+	Connected *bool      `json:"connected,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+type monitorStatus struct {
+	Ready          bool      `json:"ready"`
+	Problems       []string  `json:"problems,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	ConfigLoadedAt time.Time `json:"config_loaded_at"`
+
+	Collectors []*componentStatus `json:"collectors"`
+	Strategies []*componentStatus `json:"strategies"`
+	Notifiers  []*componentStatus `json:"notifiers"`
+}
+
+func (m *Monitor) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	problems := m.readiness()
+
+	m.mutex.Lock()
+	gen := m.current
+	result := &monitorStatus{
+		Ready:          len(problems) == 0,
+		Problems:       problems,
+		StartedAt:      m.startedAt,
+		ConfigLoadedAt: m.loadedAt,
+		Collectors:     make([]*componentStatus, 0, len(gen.collectors)),
+		Strategies:     make([]*componentStatus, 0, len(gen.strategies)),
+		Notifiers:      make([]*componentStatus, 0, len(gen.notifiers)),
+	}
+	m.mutex.Unlock()
+
+	for _, name := range sortedNames(gen.collectors) {
+		col := gen.collectors[name]
+		item := &componentStatus{Name: name, Type: col.componentType, Snapshot: col.tracker.Snapshot()}
+		if checkedAt, err := col.connection.get(); !checkedAt.IsZero() {
+			connected := err == nil
+			item.Connected, item.CheckedAt = &connected, &checkedAt
+		}
+		result.Collectors = append(result.Collectors, item)
+	}
+	for _, name := range sortedNames(gen.strategies) {
+		strata := gen.strategies[name]
+		result.Strategies = append(result.Strategies, &componentStatus{Name: name, Type: strata.componentType, Snapshot: strata.tracker.Snapshot()})
+	}
+	for _, name := range gen.notifierNames {
+		not := gen.notifiers[name]
+		result.Notifiers = append(result.Notifiers, &componentStatus{Name: name, Type: not.componentType, Snapshot: not.tracker.Snapshot()})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleAlerts queries the alert journal, filtered by the since, until, pair, strategy and
+// limit query parameters.
+func (m *Monitor) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+	filter := &journal.Filter{Pair: query.Get("pair"), Strategy: query.Get("strategy")}
+
+	var err error
+	if filter.Since, err = journal.ParseTime(query.Get("since"), now); err != nil {
+		http.Error(w, "since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Until, err = journal.ParseTime(query.Get("until"), now); err != nil {
+		http.Error(w, "until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); len(limit) > 0 {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "limit: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := m.journal.Query(filter)
+	if errors.Is(err, journal.ErrClosed) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []*journal.Entry{}
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handlePrices queries the price storage for the pair query parameter, optionally of one
+// collector. It returns the last kline closed at or before at if given, or else the klines
+// opened within since and until.
+func (m *Monitor) handlePrices(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+	collectorName, pair := query.Get("collector"), query.Get("pair")
+	if len(pair) == 0 {
+		http.Error(w, "pair is required", http.StatusBadRequest)
+		return
+	}
+
+	if at := query.Get("at"); len(at) > 0 {
+		t, err := journal.ParseTime(at, now)
+		if err != nil {
+			http.Error(w, "at: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		k, err := m.store.PriceAt(collectorName, pair, t)
+		if errors.Is(err, storage.ErrClosed) || errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, k)
+		return
+	}
+
+	since, err := journal.ParseTime(query.Get("since"), now)
+	if err != nil {
+		http.Error(w, "since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	until, err := journal.ParseTime(query.Get("until"), now)
+	if err != nil {
+		http.Error(w, "until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if until.IsZero() {
+		until = now
+	}
+
+	klines, err := m.store.Range(collectorName, pair, since, until)
+	if errors.Is(err, storage.ErrClosed) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if klines == nil {
+		klines = []*storage.Kline{}
+	}
+
+	writeJSON(w, http.StatusOK, klines)
+}
+
+// handleSilences lists the silences on GET, silences the alerts matching the strategy and
+// pair query parameters for a duration or until a time on POST, and ends their silence on DELETE.
+// POST and DELETE require the token of the server if it has one.
+func (m *Monitor) handleSilences(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	strategy, pair := query.Get("strategy"), query.Get("pair")
+
+	if r.Method != http.MethodGet && !m.authorized(r) {
+		w.Header().Set("www-authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		silences := m.silencer.List()
+		if silences == nil {
+			silences = []*silence.Silence{}
+		}
+		writeJSON(w, http.StatusOK, silences)
+	case http.MethodPost:
+		until, err := parseUntil(query.Get("duration"), query.Get("until"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		created := &silence.Silence{Strategy: strategy, Pair: pair, Until: until}
+		m.silencer.Add(created)
+		writeJSON(w, http.StatusCreated, created)
+	case http.MethodDelete:
+		if !m.silencer.Remove(strategy, pair) {
+			http.Error(w, "no such silence", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+var ackPage = template.Must(template.New("ack").Parse(`<html>
+<body>
+<p>{{.Question}}</p>
+<pre style="font-family:inherit;white-space:pre-wrap">{{.Message}}</pre>
+<form method="post"><button type="submit">{{.Button}}</button></form>
+</body>
+</html>
+`))
+
+// authorized reports whether r carries the token of the server as a bearer token, or the
+// server has none.
+func (m *Monitor) authorized(r *http.Request) bool {
+	expected := m.token()
+	if len(expected) == 0 {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// token returns the token of the server, empty if it has none.
+func (m *Monitor) token() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.current.server == nil {
+		return ""
+	}
+	return m.current.server.token
+}
+
+// handleAck acknowledges the alert of the id query parameter on POST, stopping its escalation.
+// The links in messages GET a page confirming it, so link previews of chat apps don't
+// acknowledge alerts.
+func (m *Monitor) handleAck(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	m.mutex.Lock()
+	texts := m.current.texts
+	m.mutex.Unlock()
+
+	var pending *escalation.Pending
+	exist := false
+	switch r.Method {
+	case http.MethodGet:
+		pending, exist = m.escalations.Get(id)
+	case http.MethodPost:
+		pending, exist = m.escalations.Ack(id)
+	default:
+		w.Header().Set("allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !exist {
+		http.Error(w, "no such alert waiting for acknowledgement, it may be acknowledged already", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("content-type", "text/html; charset=utf-8")
+		_ = ackPage.Execute(w, map[string]string{
+			"Question": texts.AcknowledgePage,
+			"Message":  pending.Message,
+			"Button":   texts.AcknowledgeButton,
+		})
+		return
+	}
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintf(w, texts.Acknowledged, pending.Message)
+}
+
+// handleEscalations lists the alerts waiting for acknowledgement. Their ids acknowledge them,
+// so they are only listed to requests carrying the token of the server, never if it has none.
+func (m *Monitor) handleEscalations(w http.ResponseWriter, r *http.Request) {
+	if !m.authorized(r) {
+		w.Header().Set("www-authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	list := m.escalations.List()
+	if len(m.token()) == 0 {
+		for _, pending := range list {
+			pending.ID = ""
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// parseUntil returns the end of a silence given either its duration or its end in RFC 3339.
+func parseUntil(duration, until string) (time.Time, error) {
+	switch {
+	case len(duration) > 0 && len(until) > 0:
+		return time.Time{}, errors.New("either duration or until, not both")
+	case len(duration) > 0:
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid duration %q", duration)
+		}
+		return time.Now().Add(d), nil
+	case len(until) > 0:
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil || !t.After(time.Now()) {
+			return time.Time{}, fmt.Errorf("invalid until %q, expecting a future RFC 3339 time", until)
+		}
+		return t, nil
+	default:
+		return time.Time{}, errors.New("duration or until required")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+func sortedNames[V any](components map[string]V) []string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
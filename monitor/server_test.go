@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/escalation"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/silence"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/storage"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAckConfirmsBeforeAcknowledging(t *testing.T) {
+	scheduler := escalation.NewScheduler()
+	defer scheduler.Stop()
+	conf := &config.Escalation{Policies: []*config.EscalationPolicy{{
+		Name:  "oncall",
+		Steps: []*config.EscalationStep{{Notifiers: []string{"fake"}}, {Notifiers: []string{"fake"}, After: "1h"}},
+	}}}
+	texts := templates.TextsOf(templates.LocaleEn)
+	policies, err := escalation.NewPolicies(conf, "http://localhost", map[string]notifier.Notifier{"fake": &fakeNotifier{}}, scheduler, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policies["oncall"].Notify("BTC <up>", "test", false); err != nil {
+		t.Fatal(err)
+	}
+	id := scheduler.List()[0].ID
+
+	m := &Monitor{current: &generation{texts: texts}, escalations: scheduler}
+	get := httptest.NewRecorder()
+	m.handleAck(get, httptest.NewRequest(http.MethodGet, "/ack?id="+id, nil))
+	if get.Code != http.StatusOK || !strings.Contains(get.Body.String(), `<form method="post">`) || !strings.Contains(get.Body.String(), "BTC &lt;up&gt;") {
+		t.Errorf("GET answered %d %q, want the confirmation page", get.Code, get.Body.String())
+	}
+	if len(scheduler.List()) != 1 {
+		t.Fatal("GET acknowledged the alert")
+	}
+
+	post := httptest.NewRecorder()
+	m.handleAck(post, httptest.NewRequest(http.MethodPost, "/ack?id="+id, nil))
+	if post.Code != http.StatusOK || !strings.HasPrefix(post.Body.String(), "Alert acknowledged") {
+		t.Errorf("POST answered %d %q, want the acknowledgement", post.Code, post.Body.String())
+	}
+	if len(scheduler.List()) != 0 {
+		t.Error("POST didn't acknowledge the alert")
+	}
+
+	again := httptest.NewRecorder()
+	m.handleAck(again, httptest.NewRequest(http.MethodPost, "/ack?id="+id, nil))
+	if again.Code != http.StatusNotFound {
+		t.Errorf("acknowledging again answered %d, want 404", again.Code)
+	}
+}
+
+func TestSilencesRequireToken(t *testing.T) {
+	m := &Monitor{current: &generation{server: &serverSettings{token: "secret"}}, silencer: silence.NewSilencer(state.New().Cache("silences"), nil)}
+	defer m.silencer.Stop()
+
+	tests := []struct {
+		method string
+		token  string
+		code   int
+	}{
+		{http.MethodPost, "", http.StatusUnauthorized},
+		{http.MethodPost, "wrong", http.StatusUnauthorized},
+		{http.MethodPost, "secret", http.StatusCreated},
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodDelete, "", http.StatusUnauthorized},
+		{http.MethodDelete, "secret", http.StatusNoContent},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/silences?strategy=btc&duration=1h", nil)
+		if len(test.token) > 0 {
+			r.Header.Set("authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		m.handleSilences(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s with token %q answered %d, want %d", test.method, test.token, w.Code, test.code)
+		}
+	}
+}
+
+func TestReachableURL(t *testing.T) {
+	tests := map[string]string{
+		":8080":                "",
+		"0.0.0.0:8080":         "",
+		"[::]:8080":            "",
+		"127.0.0.1:8080":       "",
+		"localhost:8080":       "",
+		"10.0.0.5:8080":        "http://10.0.0.5:8080",
+		"monitor.example:8080": "http://monitor.example:8080",
+	}
+	for listen, want := range tests {
+		if got := reachableURL(listen); got != want {
+			t.Errorf("reachableURL(%q) = %q, want %q", listen, got, want)
+		}
+	}
+}
+
+func TestEscalationsHideIdsWithoutToken(t *testing.T) {
+	scheduler := escalation.NewScheduler()
+	defer scheduler.Stop()
+	conf := &config.Escalation{Policies: []*config.EscalationPolicy{{
+		Name:  "oncall",
+		Steps: []*config.EscalationStep{{Notifiers: []string{"fake"}}, {Notifiers: []string{"fake"}, After: "1h"}},
+	}}}
+	policies, err := escalation.NewPolicies(conf, "http://monitor.example", map[string]notifier.Notifier{"fake": &fakeNotifier{}}, scheduler, templates.TextsOf(templates.LocaleEn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policies["oncall"].Notify("BTC up", "test", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		server *serverSettings
+		token  string
+		code   int
+		ids    bool
+	}{
+		{name: "no token", server: &serverSettings{}, code: http.StatusOK},
+		{name: "missing token", server: &serverSettings{token: "secret"}, code: http.StatusUnauthorized},
+		{name: "token", server: &serverSettings{token: "secret"}, token: "secret", code: http.StatusOK, ids: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &Monitor{current: &generation{server: test.server}, escalations: scheduler}
+			r := httptest.NewRequest(http.MethodGet, "/escalations", nil)
+			if len(test.token) > 0 {
+				r.Header.Set("authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			m.handleEscalations(w, r)
+			if w.Code != test.code {
+				t.Fatalf("answered %d, want %d", w.Code, test.code)
+			}
+			if ids := strings.Contains(w.Body.String(), `"id"`); w.Code == http.StatusOK && ids != test.ids {
+				t.Errorf("listed ids %v in %s, want %v", ids, w.Body.String(), test.ids)
+			}
+		})
+	}
+}
+
+func TestPricesServeRecordedWindows(t *testing.T) {
+	store := storage.New()
+	if err := store.Open(&storage.Settings{Path: filepath.Join(t.TempDir(), "prices.db"), CompactInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	openTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := &recordingCollector{name: "binance", store: store}
+	recorder.recordWindow(&collector.WindowPrice{
+		Symbol1: "BTC", Symbol2: "USDT",
+		OpenPrice: 100, HighPrice: 120, LowPrice: 90, ClosePrice: 110,
+		OpenTime:  uint64(openTime.UnixMilli()),
+		CloseTime: uint64(openTime.Add(time.Hour).UnixMilli()),
+	})
+
+	m := &Monitor{store: store}
+	tests := []struct {
+		query string
+		code  int
+		want  string
+	}{
+		{query: "", code: http.StatusBadRequest},
+		{query: "pair=BTC-USDT&at=2024-01-01T01:00:00Z", code: http.StatusOK, want: `"high": 120`},
+		{query: "pair=BTC-USDT&at=2024-01-01T00:30:00Z", code: http.StatusNotFound},
+		{query: "pair=BTC-USDT&collector=binance&since=2024-01-01T00:00:00Z", code: http.StatusOK, want: `"low": 90`},
+		{query: "pair=BTC-USDT&since=2024-01-01T00:00:01Z", code: http.StatusOK, want: "[]"},
+		{query: "pair=BTC-USDT&until=yesterday", code: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.handlePrices(w, httptest.NewRequest(http.MethodGet, "/prices?"+test.query, nil))
+		if w.Code != test.code || !strings.Contains(w.Body.String(), test.want) {
+			t.Errorf("%q answered %d %q, want %d with %q", test.query, w.Code, w.Body.String(), test.code, test.want)
+		}
+	}
+}
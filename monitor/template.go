@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"context"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/templates"
+	log "github.com/sirupsen/logrus"
+)
+
+// templatedNotifier renders the alerts of a notifier with its own templates in place of the
+// message of the strategy. Messages without template data are sent as is.
+type templatedNotifier struct {
+	name      string
+	target    notifier.Notifier
+	templates *templates.Set
+}
+
+func (t *templatedNotifier) Notify(msg, from string, throttle bool) error {
+	return t.target.Notify(msg, from, throttle)
+}
+
+func (t *templatedNotifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	if details != nil && details.Data != nil {
+		rendered, err := t.templates.Render(details.Data, details.State == notifier.StateResolved)
+		if err != nil {
+			log.Warnf("notifier %s sends the message of the strategy: %v", t.name, err)
+		} else {
+			msg = rendered
+		}
+	}
+	return notifier.Send(t.target, msg, from, throttle, details)
+}
+
+func (t *templatedNotifier) Wait(ctx context.Context) error {
+	if waiter, ok := t.target.(notifier.Waiter); ok {
+		return waiter.Wait(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"github.com/azraeljack/crypto-monitor/metrics"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/status"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// trackedNotifier records the outcome of every notification in the notifier's tracker, and
+// reports it to the details of the notification. Notifications the notifier queued are
+// reported by the notifier once sent.
+type trackedNotifier struct {
+	name    string
+	target  notifier.Notifier
+	tracker *status.Tracker
+}
+
+func (t *trackedNotifier) Notify(msg, from string, throttle bool) error {
+	start := time.Now()
+	return t.record(from, start, t.target.Notify(msg, from, throttle), nil)
+}
+
+func (t *trackedNotifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	start := time.Now()
+	return t.record(from, start, notifier.Send(t.target, msg, from, throttle, details.ReportingAs(t.name)), details)
+}
+
+func (t *trackedNotifier) Wait(ctx context.Context) error {
+	if waiter, ok := t.target.(notifier.Waiter); ok {
+		return waiter.Wait(ctx)
+	}
+	return nil
+}
+
+func (t *trackedNotifier) record(from string, start time.Time, err error, details *notifier.Details) error {
+	if errors.Is(err, notifier.ErrGrouped) {
+		log.Infof("notifier %s queued message from %s", t.name, from)
+		t.tracker.Count("queued")
+		metrics.Notified(t.name, "queued", start)
+		return nil
+	}
+	details.Report(t.name, err)
+
+	switch {
+	case err == nil:
+		t.tracker.Record("sent")
+		metrics.Notified(t.name, "sent", start)
+	case errors.Is(err, notifier.ErrThrottled):
+		log.Infof("notifier %s throttled message from %s", t.name, from)
+		t.tracker.Count("throttled")
+		metrics.Notified(t.name, "throttled", start)
+	case errors.Is(err, notifier.ErrSilenced):
+		log.Infof("notifier %s silenced message from %s", t.name, from)
+		t.tracker.Count("silenced")
+		metrics.Notified(t.name, "silenced", start)
+	default:
+		log.Errorf("notifier %s failed: %v", t.name, err)
+		t.tracker.Error(err)
+		metrics.Notified(t.name, "failed", start)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"strings"
+	"time"
+)
+
+// Labels and values price alerts carry, for notifiers highlighting them.
+const (
+	LabelPair      = "pair"
+	LabelExchange  = "exchange"
+	LabelDirection = "direction"
+
+	ValuePrice          = "price"
+	ValueAbsoluteChange = "absolute_change"
+	ValueRelativeChange = "relative_change"
+)
+
+// Field is a named value shown along with an alert, e.g. as a row of a card.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Alert is a structured notification. Notifiers implementing AlertNotifier render it natively,
+// e.g. as a card, the others are sent its plain text.
+type Alert struct {
+	Title string
+	// Body is the whole message as plain text, usually rendered from a template
+	Body     string
+	Severity Severity
+	// State is "firing" or "resolved", empty for plain messages
+	State    string
+	Strategy string
+	// Incident identifies the alert within its strategy across its firing and resolved messages
+	Incident string
+	Time     time.Time
+
+	// Labels identify what the alert is about, e.g. its pair, Fields are shown along with it
+	// and Values are the numbers behind it
+	Labels map[string]string
+	Fields []*Field
+	Values map[string]float64
+	// Price is the window price behind a price alert, nil for others
+	Price *collector.WindowPrice
+}
+
+// Text renders the alert as plain text: its body, or its title and fields if it has none.
+func (a *Alert) Text() string {
+	if len(a.Body) > 0 {
+		return a.Body
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString(a.Title)
+	for _, field := range a.Fields {
+		fmt.Fprintf(builder, "\n- %s: %s", field.Name, field.Value)
+	}
+	return builder.String()
+}
+
+// AlertNotifier is implemented by notifiers rendering alerts themselves.
+type AlertNotifier interface {
+	NotifyAlert(alert *Alert, from string, throttle bool) error
+}
+
+// SendAlert delivers alert through n, natively if n is an AlertNotifier, or else as its plain
+// text along with details, which may be nil.
+func SendAlert(n Notifier, alert *Alert, from string, throttle bool, details *Details) error {
+	if an, ok := n.(AlertNotifier); ok {
+		return an.NotifyAlert(alert, from, throttle)
+	}
+
+	withAlert := &Details{Strategy: alert.Strategy, Severity: alert.Severity, State: alert.State, Incident: alert.Incident, Price: alert.Price}
+	if details != nil {
+		copied := *details
+		withAlert = &copied
+	}
+	withAlert.Alert = alert
+	return Send(n, alert.Text(), from, throttle, withAlert)
+}
+
+// AlertOf returns the alert of details with msg as its text, or an alert made of msg and
+// details, which may be nil, if there is none. The alert of details is reduced to its labels
+// along with msg if msg isn't its text.
+func AlertOf(msg string, details *Details) *Alert {
+	if details == nil {
+		return &Alert{Title: (&Details{}).Title(msg), Body: msg, Time: time.Now()}
+	}
+	if details.Alert != nil {
+		if details.Alert.Text() == msg {
+			return details.Alert
+		}
+		// a notifier in between changed the message, e.g. to render its own template, whose
+		// text the title and fields of the original alert may not match anymore
+		copied := *details.Alert
+		copied.Title = (&Details{}).Title(msg)
+		copied.Body = msg
+		copied.Fields, copied.Values = nil, nil
+		return &copied
+	}
+	return &Alert{
+		Title:    details.Title(msg),
+		Body:     msg,
+		Severity: details.Severity,
+		State:    details.State,
+		Strategy: details.Strategy,
+		Incident: details.Incident,
+		Time:     time.Now(),
+		Price:    details.Price,
+	}
+}
@@ -0,0 +1,40 @@
+package notifier
+
+import (
+	"testing"
+)
+
+func TestAlertOf(t *testing.T) {
+	alert := &Alert{
+		Title:  "BTC - USDT ↑ 5%",
+		Body:   "发现价格波动：\n- 交易对：BTC - USDT",
+		Labels: map[string]string{LabelPair: "BTC-USDT"},
+		Fields: []*Field{{Name: "当前价格", Value: "105"}},
+		Values: map[string]float64{ValuePrice: 105},
+	}
+	details := &Details{Alert: alert}
+
+	if got := AlertOf(alert.Body, details); got != alert {
+		t.Errorf("got %+v, want the alert of the details as is", got)
+	}
+
+	msg := "BTC moved\nto 105"
+	got := AlertOf(msg, details)
+	if got.Title != "BTC moved" || got.Body != msg {
+		t.Errorf("got title %q and body %q, want them from the message", got.Title, got.Body)
+	}
+	if got.Fields != nil || got.Values != nil {
+		t.Errorf("got fields %v and values %v of the original alert", got.Fields, got.Values)
+	}
+	if got.Labels[LabelPair] != "BTC-USDT" {
+		t.Errorf("got labels %v, want the original ones", got.Labels)
+	}
+	if len(alert.Fields) != 1 || alert.Title != "BTC - USDT ↑ 5%" {
+		t.Error("the original alert was changed")
+	}
+
+	made := AlertOf("BTC moved", &Details{Strategy: "btc", State: "firing", Incident: "BTC-USDT"})
+	if made.Strategy != "btc" || made.State != "firing" || made.Incident != "BTC-USDT" {
+		t.Errorf("got %+v, want the strategy, state and incident of the details", made)
+	}
+}
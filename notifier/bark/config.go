@@ -0,0 +1,17 @@
+package bark
+
+type Config struct {
+	Timeout  string `json:"timeout"`
+	Throttle string `json:"throttle"`
+
+	ServerURL string `json:"server_url"`
+	DeviceKey string `json:"device_key"`
+	Group     string `json:"group"`
+
+	// Levels maps a severity name to a bark interruption level
+	// (passive, active, timeSensitive or critical), Sounds maps it to a sound.
+	Levels map[string]string `json:"levels"`
+	Sounds map[string]string `json:"sounds"`
+	// Volume of critical alerts which ring even in silent mode, from 0 to 10.
+	Volume int `json:"volume"`
+}
@@ -0,0 +1,9 @@
+package bark
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("bark", NewBarkNotifier)
+}
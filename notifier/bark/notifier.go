@@ -0,0 +1,143 @@
+package bark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultServerURL = "https://api.day.app"
+	levelCritical    = "critical"
+)
+
+var defaultLevels = map[notifier.Severity]string{
+	notifier.SeverityInfo:     "passive",
+	notifier.SeverityWarning:  "timeSensitive",
+	notifier.SeverityCritical: levelCritical,
+}
+
+type Message struct {
+	DeviceKey string `json:"device_key"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Level     string `json:"level"`
+	Sound     string `json:"sound,omitempty"`
+	Volume    int    `json:"volume,omitempty"`
+	Group     string `json:"group,omitempty"`
+}
+
+func (m *Message) ToJSON() []byte {
+	raw, _ := json.Marshal(m)
+	return raw
+}
+
+type Response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewBarkNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	if len(conf.ServerURL) == 0 {
+		conf.ServerURL = defaultServerURL
+	}
+	if conf.Volume == 0 {
+		conf.Volume = 5
+	}
+
+	config.Require(&errs, "device_key", conf.DeviceKey)
+	notifier.CheckSeverityKeys(&errs, "levels", conf.Levels)
+	notifier.CheckSeverityKeys(&errs, "sounds", conf.Sounds)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (b *Notifier) Notify(msg, from string, throttle bool) error {
+	return b.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (b *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending bark notification...")
+	log.Debugf("bark payload: %v", msg)
+
+	if throttle && !b.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	payload := &Message{
+		DeviceKey: b.config.DeviceKey,
+		Title:     details.Title(msg),
+		Body:      msg,
+		Level:     b.levelOf(details.Severity),
+		Sound:     b.config.Sounds[details.Severity.String()],
+		Group:     b.config.Group,
+	}
+	if payload.Level == levelCritical {
+		payload.Volume = b.config.Volume
+	}
+
+	url := strings.TrimSuffix(b.config.ServerURL, "/") + "/push"
+	request, err := http.NewRequestWithContext(b.ctx, http.MethodPost, url, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/json; charset=utf-8")
+
+	resp, err := b.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result := &Response{}
+	if err := json.Unmarshal(body, result); err != nil || resp.StatusCode != http.StatusOK || result.Code != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	log.Info("successfully notified via bark")
+	return nil
+}
+
+func (b *Notifier) levelOf(severity notifier.Severity) string {
+	if level, ok := b.config.Levels[severity.String()]; ok {
+		return level
+	}
+	return defaultLevels[severity]
+}
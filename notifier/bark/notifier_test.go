@@ -0,0 +1,66 @@
+package bark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotifyDetails(t *testing.T) {
+	tests := []struct {
+		severity notifier.Severity
+		want     Message
+	}{
+		{severity: notifier.SeverityInfo, want: Message{Level: "passive"}},
+		{severity: notifier.SeverityWarning, want: Message{Level: "active", Sound: "bell"}},
+		{severity: notifier.SeverityCritical, want: Message{Level: "critical", Volume: 5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.severity.String(), func(t *testing.T) {
+			var request *http.Request
+			message := &Message{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				_ = json.NewDecoder(r.Body).Decode(message)
+				fmt.Fprint(w, `{"code": 200, "message": "success"}`)
+			}))
+			defer server.Close()
+
+			rawConf := fmt.Sprintf(`{"type": "bark", "server_url": %q, "device_key": "device", "group": "crypto",
+				"levels": {"warning": "active"}, "sounds": {"warning": "bell"}}`, server.URL)
+			n, err := NewBarkNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := notifier.Send(n, "价格波动\nBTC", "test", false, &notifier.Details{Severity: test.severity}); err != nil {
+				t.Fatal(err)
+			}
+
+			want := test.want
+			want.DeviceKey, want.Title, want.Body, want.Group = "device", "价格波动", "价格波动\nBTC", "crypto"
+			if request.URL.Path != "/push" || *message != want {
+				t.Errorf("request to %s with %+v, want %+v", request.URL.Path, *message, want)
+			}
+		})
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code": 400, "message": "failed to get device token"}`)
+	}))
+	defer server.Close()
+
+	n, err := NewBarkNotifier(context.Background(), []byte(fmt.Sprintf(`{"type": "bark", "server_url": %q, "device_key": "device"}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify("alert", "test", false); err == nil {
+		t.Error("failed request reported as sent")
+	}
+}
@@ -0,0 +1,18 @@
+package dingtalk
+
+type Mention struct {
+	Mobiles []string `json:"mobiles"`
+	UserIDs []string `json:"user_ids"`
+	All     bool     `json:"all"`
+}
+
+type Config struct {
+	Timeout    string `json:"timeout"`
+	WebhookURL string `json:"webhook_url"`
+	Secret     string `json:"secret"`
+	Throttle   string `json:"throttle"`
+	MsgType    string `json:"msg_type"`
+
+	// Mentions maps a severity name to the users to @ for notifications of that severity.
+	Mentions map[string]*Mention `json:"mentions"`
+}
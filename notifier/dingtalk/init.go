@@ -0,0 +1,9 @@
+package dingtalk
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("dingtalk", NewDingTalkNotifier)
+}
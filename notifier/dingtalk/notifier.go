@@ -0,0 +1,203 @@
+package dingtalk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TextContent struct {
+	Content string `json:"content"`
+}
+
+type MarkdownContent struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type At struct {
+	AtMobiles []string `json:"atMobiles,omitempty"`
+	AtUserIds []string `json:"atUserIds,omitempty"`
+	IsAtAll   bool     `json:"isAtAll"`
+}
+
+type NotificationMsg struct {
+	MsgType  string           `json:"msgtype"`
+	Text     *TextContent     `json:"text,omitempty"`
+	Markdown *MarkdownContent `json:"markdown,omitempty"`
+	At       *At              `json:"at,omitempty"`
+}
+
+func (n *NotificationMsg) ToJSON() []byte {
+	raw, _ := json.Marshal(n)
+	return raw
+}
+
+type Response struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewDingTalkNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	if len(conf.MsgType) == 0 {
+		conf.MsgType = "markdown"
+	}
+
+	config.Require(&errs, "webhook_url", conf.WebhookURL)
+	config.OneOf(&errs, "msg_type", conf.MsgType, "text", "markdown")
+	notifier.CheckSeverityKeys(&errs, "mentions", conf.Mentions)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (d *Notifier) Notify(msg, from string, throttle bool) error {
+	return d.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (d *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending dingtalk notification...")
+	log.Debugf("dingtalk payload: %v", msg)
+
+	if throttle && !d.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	payload := d.buildMessage(msg, details)
+
+	webhookURL, err := d.signedURL(time.Now())
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(d.ctx, http.MethodPost, webhookURL, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/json")
+
+	resp, err := d.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	result := &Response{}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("unexpected response: %s", body)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("[%d] %s", result.ErrCode, result.ErrMsg)
+	}
+	log.Info("successfully notified via dingtalk")
+	return nil
+}
+
+func (d *Notifier) buildMessage(msg string, details *notifier.Details) *NotificationMsg {
+	payload := &NotificationMsg{MsgType: d.config.MsgType}
+
+	mention := d.config.Mentions[details.Severity.String()]
+	if mention != nil {
+		payload.At = &At{
+			AtMobiles: mention.Mobiles,
+			AtUserIds: mention.UserIDs,
+			IsAtAll:   mention.All,
+		}
+	}
+
+	if d.config.MsgType != "markdown" {
+		payload.Text = &TextContent{Content: msg}
+		return payload
+	}
+
+	title := details.Title(msg)
+
+	text := &strings.Builder{}
+	if len(details.Headline()) > 0 {
+		text.WriteString(fmt.Sprintf("### %s\n\n", details.Headline()))
+	}
+	// dingtalk markdown needs blank lines between paragraphs to keep line breaks
+	text.WriteString(strings.ReplaceAll(msg, "\n", "\n\n"))
+	if mention != nil {
+		// mentions only take effect if the @ also shows up in the markdown text
+		text.WriteString("\n\n")
+		for _, mobile := range mention.Mobiles {
+			text.WriteString("@" + mobile + " ")
+		}
+		for _, userID := range mention.UserIDs {
+			text.WriteString("@" + userID + " ")
+		}
+	}
+
+	payload.Markdown = &MarkdownContent{Title: title, Text: text.String()}
+	return payload
+}
+
+// signedURL appends the timestamp and signature required when the robot uses the "sign" security setting.
+func (d *Notifier) signedURL(now time.Time) (string, error) {
+	if len(d.config.Secret) == 0 {
+		return d.config.WebhookURL, nil
+	}
+
+	webhookURL, err := url.Parse(d.config.WebhookURL)
+	if err != nil {
+		return "", err
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(d.config.Secret))
+	mac.Write([]byte(timestamp + "\n" + d.config.Secret))
+
+	query := webhookURL.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	webhookURL.RawQuery = query.Encode()
+
+	return webhookURL.String(), nil
+}
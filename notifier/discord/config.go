@@ -0,0 +1,9 @@
+package discord
+
+type Config struct {
+	Timeout    string `json:"timeout"`
+	WebhookURL string `json:"webhook_url"`
+	Throttle   string `json:"throttle"`
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url"`
+}
@@ -0,0 +1,9 @@
+package discord
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("discord", NewDiscordNotifier)
+}
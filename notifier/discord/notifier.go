@@ -0,0 +1,150 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	colorRising  = 0x2eb886
+	colorFalling = 0xe01e5a
+
+	// the most characters discord accepts in a message content and in an embed description
+	maxContent          = 2000
+	maxEmbedDescription = 4096
+)
+
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type Embed struct {
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Color       int           `json:"color"`
+	Fields      []*EmbedField `json:"fields,omitempty"`
+	Timestamp   string        `json:"timestamp,omitempty"`
+}
+
+type Message struct {
+	Content   string   `json:"content,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	AvatarURL string   `json:"avatar_url,omitempty"`
+	Embeds    []*Embed `json:"embeds,omitempty"`
+}
+
+func (m *Message) ToJSON() []byte {
+	raw, _ := json.Marshal(m)
+	return raw
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewDiscordNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	config.Require(&errs, "webhook_url", conf.WebhookURL)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (d *Notifier) Notify(msg, from string, throttle bool) error {
+	return d.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (d *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending discord notification...")
+	log.Debugf("discord payload: %v", msg)
+
+	if throttle && !d.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	payload := &Message{
+		Content:   truncate(msg, maxContent),
+		Username:  d.config.Username,
+		AvatarURL: d.config.AvatarURL,
+	}
+	if price := details.Price; price != nil {
+		color := colorRising
+		if !price.Rising() {
+			color = colorFalling
+		}
+		payload.Content = ""
+		payload.Embeds = []*Embed{{
+			Title:       details.Headline(),
+			Description: truncate(msg, maxEmbedDescription),
+			Color:       color,
+			Fields: []*EmbedField{
+				{Name: "Price", Value: fmt.Sprintf("%v", price.ClosePrice), Inline: true},
+				{Name: "Change", Value: fmt.Sprintf("%v (%v%%)", price.AbsolutePriceChange, price.RelativePriceChange), Inline: true},
+				{Name: "Order count", Value: fmt.Sprintf("%v", price.OrderCount), Inline: true},
+			},
+			Timestamp: time.Now().Format(time.RFC3339),
+		}}
+	}
+
+	request, err := http.NewRequestWithContext(d.ctx, http.MethodPost, d.config.WebhookURL, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/json")
+
+	resp, err := d.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// discord answers 204 No Content on success unless asked to wait for the message
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		errorMsg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, errorMsg)
+	}
+	log.Info("successfully notified via discord")
+	return nil
+}
+
+// truncate cuts text to at most limit characters, discord rejects longer contents.
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
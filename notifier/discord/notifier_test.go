@@ -0,0 +1,55 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNotifyTruncates(t *testing.T) {
+	var message *Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message = &Message{}
+		_ = json.NewDecoder(r.Body).Decode(message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n, err := NewDiscordNotifier(context.Background(), []byte(fmt.Sprintf(`{"type": "discord", "webhook_url": %q}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := strings.Repeat("价格波动", 1500)
+
+	if err := n.Notify(msg, "test", false); err != nil {
+		t.Fatal(err)
+	}
+	if count := utf8.RuneCountInString(message.Content); count != maxContent || !strings.HasSuffix(message.Content, "…") {
+		t.Errorf("content of %d characters, want %d ending with an ellipsis", count, maxContent)
+	}
+
+	price := &collector.WindowPrice{Symbol1: "BTC", Symbol2: "USDT", ClosePrice: 105, AbsolutePriceChange: 5, RelativePriceChange: 5}
+	if err := n.(*Notifier).NotifyDetails(msg, "test", false, &notifier.Details{Price: price}); err != nil {
+		t.Fatal(err)
+	}
+	if len(message.Embeds) != 1 || len(message.Content) > 0 {
+		t.Fatalf("got content %q and %d embeds, want a single embed", message.Content, len(message.Embeds))
+	}
+	if count := utf8.RuneCountInString(message.Embeds[0].Description); count != maxEmbedDescription {
+		t.Errorf("embed description of %d characters, want %d", count, maxEmbedDescription)
+	}
+
+	if err := n.Notify("BTC moved", "test", false); err != nil {
+		t.Fatal(err)
+	}
+	if message.Content != "BTC moved" {
+		t.Errorf("content %q, want the short message as is", message.Content)
+	}
+}
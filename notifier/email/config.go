@@ -0,0 +1,15 @@
+package email
+
+type Config struct {
+	Host          string   `json:"host"`
+	Port          int      `json:"port"`
+	Username      string   `json:"username"`
+	Password      string   `json:"password"`
+	StartTLS      *bool    `json:"starttls"`
+	From          string   `json:"from"`
+	To            []string `json:"to"`
+	SubjectPrefix string   `json:"subject_prefix"`
+	Timeout       string   `json:"timeout"`
+	Throttle      string   `json:"throttle"`
+	Digest        string   `json:"digest"`
+}
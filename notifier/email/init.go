@@ -0,0 +1,9 @@
+package email
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("email", NewEmailNotifier)
+}
@@ -0,0 +1,123 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("EmailNotification").Parse(`<html>
+<body>
+{{range .}}<div style="margin-bottom:16px">
+<div style="color:#888;font-size:12px">{{.Time.Format "2006-01-02 15:04:05"}} · {{.From}}</div>
+<pre style="font-family:inherit;white-space:pre-wrap;margin:4px 0">{{.Msg}}</pre>
+</div>
+{{end}}</body>
+</html>
+`))
+
+type entry struct {
+	Time time.Time
+	From string
+	// Title is the title of the alert, the subject of a message of a single entry
+	Title string
+	Msg   string
+
+	// details learn the outcome of an entry queued for a digest
+	details *notifier.Details
+}
+
+// report passes the outcome of sending the entry to its details.
+func (e *entry) report(err error) {
+	e.details.Report("email", err)
+}
+
+func subjectOf(prefix string, entries []*entry) string {
+	subject := ""
+	if len(entries) == 1 {
+		subject = entries[0].Title
+	} else {
+		subject = fmt.Sprintf("%d alerts since %s", len(entries), entries[0].Time.Format("2006-01-02 15:04:05"))
+	}
+	if len(prefix) > 0 {
+		subject = prefix + " " + subject
+	}
+	return subject
+}
+
+func plainBody(entries []*entry) string {
+	builder := &strings.Builder{}
+	for i, e := range entries {
+		if i > 0 {
+			builder.WriteString("\r\n----\r\n\r\n")
+		}
+		if len(entries) > 1 {
+			builder.WriteString(fmt.Sprintf("[%s] %s\r\n", e.Time.Format("2006-01-02 15:04:05"), e.From))
+		}
+		builder.WriteString(strings.ReplaceAll(e.Msg, "\n", "\r\n"))
+	}
+	return builder.String()
+}
+
+// buildMessage renders a multipart/alternative email with plain text and HTML bodies.
+func buildMessage(from string, to []string, subject string, entries []*entry) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	buf.WriteString("\r\n")
+
+	plainPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plainPart.Write([]byte(plainBody(entries))); err != nil {
+		return nil, err
+	}
+
+	htmlPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := htmlTemplate.Execute(htmlPart, entries); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	random := make([]byte, 8)
+	_, _ = rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
@@ -0,0 +1,254 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/lifecycle"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxPending bounds the notifications kept for the next digest while sending fails.
+const maxPending = 1000
+
+var errDropped = errors.New("dropped from the digest after failing to be sent")
+
+type Notifier struct {
+	config  *Config
+	timeout time.Duration
+
+	throttler *notifier.Throttler
+
+	digest       time.Duration
+	pending      []*entry
+	pendingMutex sync.Mutex
+	workers      sync.WaitGroup
+
+	ctx context.Context
+}
+
+func NewEmailNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	if conf.Port == 0 {
+		conf.Port = 587
+	}
+	if conf.StartTLS == nil {
+		startTLS := true
+		conf.StartTLS = &startTLS
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 10*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	// an empty digest interval disables batching
+	digest := config.ParseDuration(&errs, "digest", conf.Digest, 0)
+
+	config.Require(&errs, "host", conf.Host)
+	config.Require(&errs, "from", conf.From)
+	if len(conf.To) == 0 {
+		errs.Addf("to", "at least one recipient is required")
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		config:    conf,
+		timeout:   timeout,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		digest:    digest,
+		ctx:       ctx,
+	}
+	if digest > 0 {
+		n.workers.Add(1)
+		go n.runDigest()
+	}
+
+	return n, nil
+}
+
+func (e *Notifier) Notify(msg, from string, throttle bool) error {
+	return e.NotifyDetails(msg, from, throttle, nil)
+}
+
+// NotifyDetails sends msg right away, or queues it for the next digest and returns
+// ErrGrouped, reporting the outcome to details once the digest is sent.
+func (e *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending email notification...")
+	log.Debugf("email payload: %v", msg)
+
+	if throttle && !e.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	item := &entry{Time: time.Now(), From: from, Title: notifier.AlertOf(msg, details).Title, Msg: msg, details: details}
+	if e.digest > 0 {
+		// reported before queueing, the digest may be sent before returning
+		details.Report("email", notifier.ErrGrouped)
+		e.pendingMutex.Lock()
+		e.pending = append(e.pending, item)
+		e.pendingMutex.Unlock()
+		log.Infof("email notification queued for the next digest")
+		return notifier.ErrGrouped
+	}
+
+	if err := e.send([]*entry{item}); err != nil {
+		return err
+	}
+	log.Info("successfully notified via email")
+	return nil
+}
+
+// Wait blocks until the pending digest is sent after the notifier's context is done.
+func (e *Notifier) Wait(ctx context.Context) error {
+	return lifecycle.Wait(ctx, &e.workers)
+}
+
+func (e *Notifier) runDigest() {
+	defer e.workers.Done()
+
+	ticker := time.NewTicker(e.digest)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.flush(false)
+		case <-e.ctx.Done():
+			e.flush(true)
+			log.Info("email digest worker exit")
+			return
+		}
+	}
+}
+
+// flush sends the pending digest. Entries failing to be sent are retried with the next one,
+// unless it's the last one.
+func (e *Notifier) flush(last bool) {
+	e.pendingMutex.Lock()
+	entries := e.pending
+	e.pending = nil
+	e.pendingMutex.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	log.Infof("sending email digest of %d notifications...", len(entries))
+	err := e.send(entries)
+	if err != nil && !last {
+		log.Errorf("email digest fail, retrying on the next one: %v", err)
+		e.requeue(entries)
+		return
+	}
+	if err != nil {
+		log.Errorf("email digest fail: %v", err)
+	} else {
+		log.Info("successfully sent email digest")
+	}
+	for _, item := range entries {
+		item.report(err)
+	}
+}
+
+// requeue puts entries which failed to be sent back ahead of the pending ones, dropping the
+// oldest beyond maxPending.
+func (e *Notifier) requeue(entries []*entry) {
+	e.pendingMutex.Lock()
+	defer e.pendingMutex.Unlock()
+
+	e.pending = append(entries, e.pending...)
+	if dropped := len(e.pending) - maxPending; dropped > 0 {
+		log.Warnf("email digest dropped %d notifications failing to be sent", dropped)
+		for _, item := range e.pending[:dropped] {
+			item.report(errDropped)
+		}
+		e.pending = e.pending[dropped:]
+	}
+}
+
+func (e *Notifier) send(entries []*entry) error {
+	msg, err := buildMessage(e.config.From, e.config.To, subjectOf(e.config.SubjectPrefix, entries), entries)
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	conn, err := net.DialTimeout("tcp", addr, e.timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(e.timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if *e.config.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: e.config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if len(e.config.Username) > 0 {
+		if err := client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(envelopeAddress(e.config.From)); err != nil {
+		return err
+	}
+	for _, to := range e.config.To {
+		if err := client.Rcpt(envelopeAddress(to)); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(msg); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// envelopeAddress strips the display name, e.g. "Monitor <bot@example.com>" becomes "bot@example.com".
+func envelopeAddress(addr string) string {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return addr
+	}
+	return parsed.Address
+}
@@ -0,0 +1,263 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"mime"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpServer is a local SMTP stand-in recording the messages it receives. It rejects the
+// first failures messages.
+type smtpServer struct {
+	listener net.Listener
+	failures int
+
+	mutex    sync.Mutex
+	messages []string
+}
+
+func newSMTPServer(t *testing.T, failures int) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &smtpServer{listener: listener, failures: failures}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"):
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			data := &strings.Builder{}
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			reply(s.receive(data.String()))
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) receive(message string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return "554 transaction failed"
+	}
+	s.messages = append(s.messages, message)
+	return "250 OK"
+}
+
+func (s *smtpServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+func (s *smtpServer) notifier(t *testing.T, ctx context.Context, digest string) *Notifier {
+	rawConf := fmt.Sprintf(`{"type": "email", "name": "email", "host": "127.0.0.1", "port": %d, "starttls": false,
+		"from": "Monitor <bot@example.com>", "to": ["ops@example.com"], "throttle": "1ns", "digest": %q}`, s.port(), digest)
+	n, err := NewEmailNotifier(ctx, []byte(rawConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.(*Notifier)
+}
+
+func waitForMessages(t *testing.T, server *smtpServer, count int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages := server.received()
+		if len(messages) >= count {
+			return messages
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d messages, want %d", len(messages), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNotifySendsRightAway(t *testing.T) {
+	server := newSMTPServer(t, 0)
+	n := server.notifier(t, context.Background(), "")
+
+	if err := n.Notify("发现价格波动：\nBTC-USDT +5%", "test", true); err != nil {
+		t.Fatal(err)
+	}
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("received %d messages, want 1", len(messages))
+	}
+	for _, want := range []string{"To: ops@example.com", "BTC-USDT +5%", "multipart/alternative"} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("message misses %q:\n%s", want, messages[0])
+		}
+	}
+}
+
+func TestSubjectIsAlertTitle(t *testing.T) {
+	server := newSMTPServer(t, 0)
+	n := server.notifier(t, context.Background(), "")
+
+	price := &collector.WindowPrice{Symbol1: "BTC", Symbol2: "USDT", AbsolutePriceChange: 5, RelativePriceChange: 5}
+	tests := []struct {
+		msg     string
+		details *notifier.Details
+		subject string
+	}{
+		{msg: "发现价格波动：\nBTC-USDT +5%", details: &notifier.Details{Price: price}, subject: "BTC-USDT ▲ 5%"},
+		{msg: "Price alert:\nBTC-USDT +5%", subject: "Price alert:"},
+	}
+	for i, test := range tests {
+		if err := n.NotifyDetails(test.msg, "test", false, test.details); err != nil {
+			t.Fatal(err)
+		}
+		messages := server.received()
+		subject := ""
+		for _, line := range strings.Split(messages[i], "\r\n") {
+			if strings.HasPrefix(line, "Subject: ") {
+				subject, _ = (&mime.WordDecoder{}).DecodeHeader(strings.TrimPrefix(line, "Subject: "))
+			}
+		}
+		if subject != test.subject {
+			t.Errorf("subject %q, want %q", subject, test.subject)
+		}
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	server := newSMTPServer(t, 1)
+	n := server.notifier(t, context.Background(), "")
+
+	if err := n.Notify("alert", "test", true); err == nil {
+		t.Fatal("rejected message reported as sent")
+	}
+}
+
+func TestDigest(t *testing.T) {
+	server := newSMTPServer(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := server.notifier(t, ctx, "50ms")
+
+	var reported []error
+	var mutex sync.Mutex
+	details := (&notifier.Details{}).WithReporter(func(_ string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reported = append(reported, err)
+	})
+	for _, msg := range []string{"first alert", "second alert"} {
+		if err := n.NotifyDetails(msg, "test", true, details); !errors.Is(err, notifier.ErrGrouped) {
+			t.Fatalf("queued alert returned %v, want %v", err, notifier.ErrGrouped)
+		}
+	}
+	if messages := server.received(); len(messages) != 0 {
+		t.Fatalf("digest sent right away")
+	}
+
+	messages := waitForMessages(t, server, 1)
+	for _, want := range []string{"first alert", "second alert", "2 alerts since"} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("digest misses %q:\n%s", want, messages[0])
+		}
+	}
+
+	// each alert is reported grouped when queued and sent with the digest
+	cancel()
+	if err := n.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(reported) != 4 || !errors.Is(reported[0], notifier.ErrGrouped) || reported[2] != nil || reported[3] != nil {
+		t.Errorf("reported %v, want each alert grouped and then sent", reported)
+	}
+}
+
+func TestDigestRetriesFailures(t *testing.T) {
+	server := newSMTPServer(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	n := server.notifier(t, ctx, "50ms")
+
+	if err := n.Notify("first alert", "test", true); !errors.Is(err, notifier.ErrGrouped) {
+		t.Fatal(err)
+	}
+	// the first digest fails, the alert goes out with the next one
+	time.Sleep(75 * time.Millisecond)
+	if err := n.Notify("second alert", "test", true); !errors.Is(err, notifier.ErrGrouped) {
+		t.Fatal(err)
+	}
+
+	messages := waitForMessages(t, server, 1)
+	for _, want := range []string{"first alert", "second alert"} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("digest misses %q:\n%s", want, messages[0])
+		}
+	}
+
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := n.Wait(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+}
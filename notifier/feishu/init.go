@@ -0,0 +1,10 @@
+package feishu
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("feishu", NewFeishuNotifier)
+	notifier.GetRegistry().Register("lark", NewFeishuNotifier)
+}
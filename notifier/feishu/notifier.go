@@ -0,0 +1,206 @@
+package feishu
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var headerColors = map[notifier.Severity]string{
+	notifier.SeverityInfo:     "blue",
+	notifier.SeverityWarning:  "orange",
+	notifier.SeverityCritical: "red",
+}
+
+type Text struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type CardHeader struct {
+	Title    *Text  `json:"title"`
+	Template string `json:"template"`
+}
+
+type CardElement struct {
+	Tag  string `json:"tag"`
+	Text *Text  `json:"text,omitempty"`
+}
+
+type Card struct {
+	Header   *CardHeader    `json:"header"`
+	Elements []*CardElement `json:"elements"`
+}
+
+type TextContent struct {
+	Text string `json:"text"`
+}
+
+type NotificationMsg struct {
+	Timestamp string       `json:"timestamp,omitempty"`
+	Sign      string       `json:"sign,omitempty"`
+	MsgType   string       `json:"msg_type"`
+	Content   *TextContent `json:"content,omitempty"`
+	Card      *Card        `json:"card,omitempty"`
+}
+
+func (n *NotificationMsg) ToJSON() []byte {
+	raw, _ := json.Marshal(n)
+	return raw
+}
+
+type Response struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewFeishuNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	if len(conf.MsgType) == 0 {
+		conf.MsgType = "interactive"
+	}
+
+	config.Require(&errs, "webhook_url", conf.WebhookURL)
+	config.OneOf(&errs, "msg_type", conf.MsgType, "text", "interactive")
+	notifier.CheckSeverityKeys(&errs, "mentions", conf.Mentions)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (f *Notifier) Notify(msg, from string, throttle bool) error {
+	return f.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (f *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending feishu notification...")
+	log.Debugf("feishu payload: %v", msg)
+
+	if throttle && !f.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	payload := f.buildMessage(msg, details)
+	f.sign(payload, time.Now())
+
+	request, err := http.NewRequestWithContext(f.ctx, http.MethodPost, f.config.WebhookURL, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/json")
+
+	resp, err := f.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	result := &Response{}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("unexpected response: %s", body)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("[%d] %s", result.Code, result.Msg)
+	}
+	log.Info("successfully notified via feishu")
+	return nil
+}
+
+func (f *Notifier) buildMessage(msg string, details *notifier.Details) *NotificationMsg {
+	mention := f.config.Mentions[details.Severity.String()]
+
+	if f.config.MsgType != "interactive" {
+		text := msg
+		if mention != nil {
+			for _, userID := range mention.UserIDs {
+				text += fmt.Sprintf(` <at user_id="%s"></at>`, userID)
+			}
+			if mention.All {
+				text += ` <at user_id="all">所有人</at>`
+			}
+		}
+		return &NotificationMsg{MsgType: "text", Content: &TextContent{Text: text}}
+	}
+
+	title := details.Title(msg)
+
+	card := &Card{
+		Header: &CardHeader{
+			Title:    &Text{Tag: "plain_text", Content: title},
+			Template: headerColors[details.Severity],
+		},
+		Elements: []*CardElement{
+			{Tag: "div", Text: &Text{Tag: "lark_md", Content: msg}},
+		},
+	}
+	if mention != nil {
+		mentions := &strings.Builder{}
+		for _, userID := range mention.UserIDs {
+			mentions.WriteString(fmt.Sprintf("<at id=%s></at> ", userID))
+		}
+		if mention.All {
+			mentions.WriteString("<at id=all></at>")
+		}
+		card.Elements = append(card.Elements, &CardElement{Tag: "div", Text: &Text{Tag: "lark_md", Content: mentions.String()}})
+	}
+
+	return &NotificationMsg{MsgType: "interactive", Card: card}
+}
+
+// sign fills in the timestamp and signature required when the bot has signature verification enabled.
+func (f *Notifier) sign(payload *NotificationMsg, now time.Time) {
+	if len(f.config.Secret) == 0 {
+		return
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	// feishu uses the string to sign as the HMAC key over an empty message
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.config.Secret))
+
+	payload.Timestamp = timestamp
+	payload.Sign = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,12 @@
+package gotify
+
+type Config struct {
+	Timeout  string `json:"timeout"`
+	Throttle string `json:"throttle"`
+
+	ServerURL string `json:"server_url"`
+	AppToken  string `json:"app_token"`
+
+	// Priorities maps a severity name to a gotify priority from 0 to 10.
+	Priorities map[string]int `json:"priorities"`
+}
@@ -0,0 +1,9 @@
+package gotify
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("gotify", NewGotifyNotifier)
+}
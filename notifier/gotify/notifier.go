@@ -0,0 +1,116 @@
+package gotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var defaultPriorities = map[notifier.Severity]int{
+	notifier.SeverityInfo:     2,
+	notifier.SeverityWarning:  5,
+	notifier.SeverityCritical: 10,
+}
+
+type Message struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+func (m *Message) ToJSON() []byte {
+	raw, _ := json.Marshal(m)
+	return raw
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewGotifyNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	config.Require(&errs, "server_url", conf.ServerURL)
+	config.Require(&errs, "app_token", conf.AppToken)
+	notifier.CheckSeverityKeys(&errs, "priorities", conf.Priorities)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (g *Notifier) Notify(msg, from string, throttle bool) error {
+	return g.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (g *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending gotify notification...")
+	log.Debugf("gotify payload: %v", msg)
+
+	if throttle && !g.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	payload := &Message{
+		Title:    details.Title(msg),
+		Message:  msg,
+		Priority: g.priorityOf(details.Severity),
+	}
+
+	url := strings.TrimSuffix(g.config.ServerURL, "/") + "/message"
+	request, err := http.NewRequestWithContext(g.ctx, http.MethodPost, url, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/json")
+	request.Header.Add("X-Gotify-Key", g.config.AppToken)
+
+	resp, err := g.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, errorMsg)
+	}
+	log.Info("successfully notified via gotify")
+	return nil
+}
+
+func (g *Notifier) priorityOf(severity notifier.Severity) int {
+	if priority, ok := g.config.Priorities[severity.String()]; ok {
+		return priority
+	}
+	return defaultPriorities[severity]
+}
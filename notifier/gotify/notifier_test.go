@@ -0,0 +1,66 @@
+package gotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotifyDetails(t *testing.T) {
+	tests := []struct {
+		severity notifier.Severity
+		priority int
+	}{
+		{severity: notifier.SeverityInfo, priority: 2},
+		{severity: notifier.SeverityWarning, priority: 5},
+		{severity: notifier.SeverityCritical, priority: 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.severity.String(), func(t *testing.T) {
+			var request *http.Request
+			message := &Message{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				_ = json.NewDecoder(r.Body).Decode(message)
+			}))
+			defer server.Close()
+
+			rawConf := fmt.Sprintf(`{"type": "gotify", "server_url": %q, "app_token": "token", "priorities": {"critical": 8}}`, server.URL+"/")
+			n, err := NewGotifyNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := notifier.Send(n, "价格波动\nBTC", "test", false, &notifier.Details{Severity: test.severity}); err != nil {
+				t.Fatal(err)
+			}
+
+			if request.URL.Path != "/message" || request.Header.Get("X-Gotify-Key") != "token" {
+				t.Errorf("unexpected request to %s with key %q", request.URL.Path, request.Header.Get("X-Gotify-Key"))
+			}
+			want := Message{Title: "价格波动", Message: "价格波动\nBTC", Priority: test.priority}
+			if *message != want {
+				t.Errorf("message %+v, want %+v", *message, want)
+			}
+		})
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	n, err := NewGotifyNotifier(context.Background(), []byte(fmt.Sprintf(`{"type": "gotify", "server_url": %q, "app_token": "token"}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify("alert", "test", false); err == nil {
+		t.Error("failed request reported as sent")
+	}
+}
@@ -0,0 +1,22 @@
+package irc
+
+type Config struct {
+	Timeout  string `json:"timeout"`
+	Throttle string `json:"throttle"`
+
+	// Server is the host:port of the IRC server.
+	Server           string   `json:"server"`
+	TLS              bool     `json:"tls"`
+	Password         string   `json:"password"`
+	Nick             string   `json:"nick"`
+	Username         string   `json:"username"`
+	RealName         string   `json:"real_name"`
+	NickServPassword string   `json:"nickserv_password"`
+	Channels         []string `json:"channels"`
+
+	// SendInterval and Burst configure the flood protection, a burst of lines
+	// may be sent at once and afterwards one line per interval.
+	SendInterval string `json:"send_interval"`
+	Burst        int    `json:"burst"`
+	QueueSize    int    `json:"queue_size"`
+}
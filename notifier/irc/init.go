@@ -0,0 +1,9 @@
+package irc
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("irc", NewIRCNotifier)
+}
@@ -0,0 +1,74 @@
+package irc
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageBytes keeps PRIVMSG lines well below the 512 byte protocol limit
+// once the server prepends our prefix.
+const maxMessageBytes = 400
+
+type message struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+func parseMessage(line string) *message {
+	line = strings.TrimRight(line, "\r\n")
+	msg := &message{}
+
+	if strings.HasPrefix(line, ":") {
+		parts := strings.SplitN(line[1:], " ", 2)
+		msg.prefix = parts[0]
+		if len(parts) < 2 {
+			return msg
+		}
+		line = parts[1]
+	}
+
+	for len(line) > 0 {
+		if strings.HasPrefix(line, ":") {
+			msg.params = append(msg.params, line[1:])
+			break
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(msg.command) == 0 {
+			msg.command = strings.ToUpper(parts[0])
+		} else {
+			msg.params = append(msg.params, parts[0])
+		}
+		if len(parts) < 2 {
+			break
+		}
+		line = strings.TrimLeft(parts[1], " ")
+	}
+
+	return msg
+}
+
+// lineBreaks drops carriage returns and NUL, servers may take them as line ends and run the
+// rest of a message as a command.
+var lineBreaks = strings.NewReplacer("\r", "", "\x00", "")
+
+// splitLines turns a multi-line notification into IRC safe lines of bounded length.
+func splitLines(msg string) []string {
+	var lines []string
+	for _, line := range strings.Split(msg, "\n") {
+		line = lineBreaks.Replace(line)
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		for len(line) > maxMessageBytes {
+			cut := maxMessageBytes
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,397 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/lifecycle"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	readTimeout = 5 * time.Minute
+	maxBackoff  = 5 * time.Minute
+)
+
+type Notifier struct {
+	config  *Config
+	timeout time.Duration
+
+	throttler *notifier.Throttler
+
+	sendInterval time.Duration
+	queue        chan string
+
+	writeMutex sync.Mutex
+	workers    sync.WaitGroup
+
+	ctx context.Context
+}
+
+func NewIRCNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 10*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+	sendInterval := config.ParseDuration(&errs, "send_interval", conf.SendInterval, 2*time.Second)
+
+	if len(conf.Username) == 0 {
+		conf.Username = conf.Nick
+	}
+	if len(conf.RealName) == 0 {
+		conf.RealName = conf.Nick
+	}
+	if conf.Burst <= 0 {
+		conf.Burst = 4
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 200
+	}
+
+	config.Require(&errs, "server", conf.Server)
+	config.Require(&errs, "nick", conf.Nick)
+	if len(conf.Channels) == 0 {
+		errs.Addf("channels", "at least one channel is required")
+	}
+	if sendInterval == 0 {
+		errs.Addf("send_interval", "must be positive")
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		config:       conf,
+		timeout:      timeout,
+		throttler:    notifier.NewThrottler(ctx, throttle),
+		sendInterval: sendInterval,
+		queue:        make(chan string, conf.QueueSize),
+		ctx:          ctx,
+	}
+	n.workers.Add(1)
+	go n.run()
+
+	return n, nil
+}
+
+// Notify queues the message for every configured channel, it is delivered once
+// the connection is up and the flood protection allows.
+func (i *Notifier) Notify(msg, from string, throttle bool) error {
+	log.Info("sending irc notification...")
+	log.Debugf("irc payload: %v", msg)
+
+	if throttle && !i.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	discarded := 0
+	for _, channel := range i.config.Channels {
+		for _, line := range splitLines(msg) {
+			select {
+			case i.queue <- fmt.Sprintf("PRIVMSG %s :%s", channel, line):
+			default:
+				log.Warnf("irc send queue full, discard line: %s", line)
+				discarded++
+			}
+		}
+	}
+	if discarded > 0 {
+		return fmt.Errorf("send queue full, %d lines discarded", discarded)
+	}
+	log.Info("irc notification queued")
+	return nil
+}
+
+// Wait blocks until the queued lines are sent and the connection is closed after the notifier's context is done.
+func (i *Notifier) Wait(ctx context.Context) error {
+	return lifecycle.Wait(ctx, &i.workers)
+}
+
+func (i *Notifier) run() {
+	defer i.workers.Done()
+
+	backoff := time.Second
+	for {
+		if i.ctx.Err() != nil {
+			log.Info("irc notifier exited")
+			return
+		}
+
+		start := time.Now()
+		err := i.session()
+		if i.ctx.Err() != nil {
+			log.Info("irc notifier exited")
+			return
+		}
+
+		// a session that stayed up for a while resets the reconnect backoff
+		if time.Since(start) > maxBackoff {
+			backoff = time.Second
+		}
+		log.Warnf("irc connection to %s lost: %v, reconnecting in %v", i.config.Server, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-i.ctx.Done():
+			log.Info("irc notifier exited")
+			return
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (i *Notifier) session() error {
+	conn, err := i.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	registered := make(chan struct{})
+	identified := make(chan bool, 1)
+	joined := make(chan string, len(i.config.Channels))
+	readErr := make(chan error, 1)
+	// the reader exits once the connection is closed on return
+	i.workers.Add(1)
+	go func() {
+		defer i.workers.Done()
+		readErr <- i.readLoop(conn, registered, identified, joined)
+	}()
+
+	if len(i.config.Password) > 0 {
+		_ = i.write(conn, "PASS "+i.config.Password)
+	}
+	_ = i.write(conn, "NICK "+i.config.Nick)
+	_ = i.write(conn, fmt.Sprintf("USER %s 0 * :%s", i.config.Username, i.config.RealName))
+
+	select {
+	case <-registered:
+	case err := <-readErr:
+		return err
+	case <-time.After(i.timeout):
+		return errors.New("registration timed out")
+	case <-i.ctx.Done():
+		_ = i.write(conn, "QUIT :shutting down")
+		return nil
+	}
+
+	log.Infof("connected to irc server %s", i.config.Server)
+	if len(i.config.NickServPassword) > 0 {
+		_ = i.write(conn, "PRIVMSG NickServ :IDENTIFY "+i.config.NickServPassword)
+		if err := i.awaitIdentify(identified, readErr); err != nil {
+			return err
+		}
+	}
+	for _, channel := range i.config.Channels {
+		_ = i.write(conn, "JOIN "+channel)
+	}
+	if err := i.awaitJoins(joined, readErr); err != nil {
+		return err
+	}
+
+	// token bucket flood protection
+	tokens := i.config.Burst
+	refill := time.NewTicker(i.sendInterval)
+	defer refill.Stop()
+
+	for {
+		queue := i.queue
+		if tokens == 0 {
+			queue = nil
+		}
+
+		select {
+		case line := <-queue:
+			if err := i.write(conn, line); err != nil {
+				i.requeue(line)
+				return err
+			}
+			tokens--
+		case <-refill.C:
+			if tokens < i.config.Burst {
+				tokens++
+			}
+		case err := <-readErr:
+			return err
+		case <-i.ctx.Done():
+			i.flushQueue(conn)
+			_ = i.write(conn, "QUIT :shutting down")
+			return nil
+		}
+	}
+}
+
+// awaitIdentify waits until services answered the identification, channels restricted to
+// registered users would refuse joining before. Without an answer in time it joins anyway.
+func (i *Notifier) awaitIdentify(identified <-chan bool, readErr <-chan error) error {
+	select {
+	case ok := <-identified:
+		if !ok {
+			log.Warnf("irc services of %s refused identifying as %s", i.config.Server, i.config.Nick)
+		}
+	case err := <-readErr:
+		return err
+	case <-time.After(i.timeout):
+		log.Warnf("irc services of %s didn't answer identifying in time", i.config.Server)
+	case <-i.ctx.Done():
+	}
+	return nil
+}
+
+// awaitJoins waits until the server confirmed joining every channel, messages to a channel
+// not joined yet would be rejected. Channels not joined in time are given up with a warning.
+func (i *Notifier) awaitJoins(joined <-chan string, readErr <-chan error) error {
+	pending := make(map[string]bool, len(i.config.Channels))
+	for _, channel := range i.config.Channels {
+		pending[strings.ToLower(channel)] = true
+	}
+
+	timeout := time.After(i.timeout)
+	for len(pending) > 0 {
+		select {
+		case channel := <-joined:
+			delete(pending, strings.ToLower(channel))
+		case err := <-readErr:
+			return err
+		case <-timeout:
+			channels := make([]string, 0, len(pending))
+			for channel := range pending {
+				channels = append(channels, channel)
+			}
+			log.Warnf("irc server %s didn't confirm joining %s in time", i.config.Server, strings.Join(channels, ", "))
+			return nil
+		case <-i.ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// requeue puts back a line which failed to be sent, to be sent once reconnected. It is
+// dropped if the queue filled up in the meantime.
+func (i *Notifier) requeue(line string) {
+	select {
+	case i.queue <- line:
+	default:
+		log.Warnf("irc send queue full, discard line: %s", line)
+	}
+}
+
+// flushQueue sends the queued lines on shutdown, regardless of the flood protection.
+func (i *Notifier) flushQueue(conn net.Conn) {
+	for {
+		select {
+		case line := <-i.queue:
+			if err := i.write(conn, line); err != nil {
+				log.Warnf("failed to flush irc send queue: %v", err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (i *Notifier) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: i.timeout}
+	if !i.config.TLS {
+		return dialer.DialContext(i.ctx, "tcp", i.config.Server)
+	}
+
+	host, _, _ := net.SplitHostPort(i.config.Server)
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+	return tlsDialer.DialContext(i.ctx, "tcp", i.config.Server)
+}
+
+// readLoop answers pings on conn and signals registration, the answer of services to
+// identifying and each channel joined, until reading fails.
+func (i *Notifier) readLoop(conn net.Conn, registered chan<- struct{}, identified chan<- bool, joined chan<- string) error {
+	reader := bufio.NewReader(conn)
+	nick := i.config.Nick
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			return err
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		log.Debugf("irc received: %s", strings.TrimSpace(line))
+
+		msg := parseMessage(line)
+		switch msg.command {
+		case "PING":
+			_ = i.write(conn, "PONG :"+strings.Join(msg.params, " "))
+		case "001":
+			if registered != nil {
+				close(registered)
+				registered = nil
+			}
+		case "900":
+			// logged in to an account
+			i.signalIdentified(identified, true)
+		case "NOTICE":
+			if !strings.HasPrefix(strings.ToLower(msg.prefix), "nickserv!") || len(msg.params) < 2 {
+				break
+			}
+			log.Debugf("irc NickServ: %s", msg.params[1])
+			// the notice asking to identify right after connecting is neither
+			text := strings.ToLower(msg.params[1])
+			switch {
+			case strings.Contains(text, "identified") || strings.Contains(text, "recognized"):
+				i.signalIdentified(identified, true)
+			case strings.Contains(text, "invalid") || strings.Contains(text, "incorrect"):
+				i.signalIdentified(identified, false)
+			}
+		case "366":
+			// end of the names list, sent once a channel is joined
+			if len(msg.params) > 1 {
+				select {
+				case joined <- msg.params[1]:
+				default:
+				}
+			}
+		case "433":
+			// nickname in use
+			nick += "_"
+			_ = i.write(conn, "NICK "+nick)
+		case "ERROR":
+			return fmt.Errorf("server error: %s", strings.Join(msg.params, " "))
+		}
+	}
+}
+
+func (i *Notifier) signalIdentified(identified chan<- bool, ok bool) {
+	select {
+	case identified <- ok:
+	default:
+	}
+}
+
+func (i *Notifier) write(conn net.Conn, line string) error {
+	i.writeMutex.Lock()
+	defer i.writeMutex.Unlock()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(i.timeout)); err != nil {
+		return err
+	}
+	_, err := conn.Write([]byte(line + "\r\n"))
+	return err
+}
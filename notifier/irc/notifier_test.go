@@ -0,0 +1,141 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ircServer accepts one client, welcomes it and confirms its identification and the channels
+// it joins after a delay, recording the lines it receives.
+type ircServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	lines    []string
+}
+
+func newIRCServer(t *testing.T, joinDelay time.Duration) *ircServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &ircServer{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			msg := parseMessage(line)
+			s.mutex.Lock()
+			s.lines = append(s.lines, strings.TrimSpace(line))
+			s.mutex.Unlock()
+
+			switch msg.command {
+			case "USER":
+				fmt.Fprintf(conn, ":server 001 bot :Welcome\r\n")
+				fmt.Fprintf(conn, ":NickServ!NickServ@services. NOTICE bot :This nickname is registered, please identify\r\n")
+			case "PRIVMSG":
+				if msg.params[0] != "NickServ" {
+					break
+				}
+				go func() {
+					time.Sleep(joinDelay)
+					s.record("identified")
+					fmt.Fprintf(conn, ":NickServ!NickServ@services. NOTICE bot :You are now identified for bot.\r\n")
+				}()
+			case "JOIN":
+				// keep reading meanwhile, to record lines sent before the join is confirmed
+				go func(channel string) {
+					time.Sleep(joinDelay)
+					s.record("366 " + channel)
+					fmt.Fprintf(conn, ":server 366 bot %s :End of /NAMES list.\r\n", channel)
+				}(msg.params[0])
+			}
+		}
+	}()
+	return s
+}
+
+func (s *ircServer) record(line string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lines = append(s.lines, line)
+}
+
+func (s *ircServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.lines...)
+}
+
+func TestSendsAfterJoining(t *testing.T) {
+	server := newIRCServer(t, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rawConf := fmt.Sprintf(`{"type": "irc", "server": %q, "nick": "bot", "nickserv_password": "secret", "channels": ["#alerts"], "timeout": "2s"}`, server.listener.Addr())
+	n, err := NewIRCNotifier(ctx, []byte(rawConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify("价格波动\nBTC", "test", false); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lines := server.received()
+		if len(lines) > 0 && lines[len(lines)-1] == "PRIVMSG #alerts :BTC" {
+			identified, joined := -1, -1
+			for i, line := range lines {
+				if line == "identified" {
+					identified = i
+				}
+				if strings.HasPrefix(line, "JOIN") && identified < 0 {
+					t.Fatalf("joined before services identified the nick, got %v", lines)
+				}
+				if line == "366 #alerts" {
+					joined = i
+				}
+				if strings.HasPrefix(line, "PRIVMSG #alerts") && joined < 0 {
+					t.Fatalf("sent %q before joining the channel, got %v", line, lines)
+				}
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the message, got %v", lines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := n.(*Notifier).Wait(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitLinesDropsCarriageReturns(t *testing.T) {
+	lines := splitLines("BTC\rQUIT :bye\r\nETH\x00")
+	if len(lines) != 2 || lines[0] != "BTCQUIT :bye" || lines[1] != "ETH" {
+		t.Errorf("got lines %q, want them without carriage returns or NUL", lines)
+	}
+}
@@ -0,0 +1,14 @@
+package matrix
+
+type Config struct {
+	Timeout  string `json:"timeout"`
+	Throttle string `json:"throttle"`
+
+	HomeserverURL string `json:"homeserver_url"`
+	AccessToken   string `json:"access_token"`
+	RoomID        string `json:"room_id"`
+	// MsgType is m.notice (default) or m.text, clients don't ping for notices.
+	MsgType string `json:"msg_type"`
+	// MaxRetries is how many times a failed send is retried, 3 by default, 0 for none.
+	MaxRetries *int `json:"max_retries"`
+}
@@ -0,0 +1,9 @@
+package matrix
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("matrix", NewMatrixNotifier)
+}
@@ -0,0 +1,184 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	msgTypeNotice = "m.notice"
+	msgTypeText   = "m.text"
+)
+
+type Message struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+func (m *Message) ToJSON() []byte {
+	raw, _ := json.Marshal(m)
+	return raw
+}
+
+type ErrorResponse struct {
+	ErrCode      string `json:"errcode"`
+	Error        string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	// transaction ids are unique per process run and reused when retrying,
+	// so the homeserver deduplicates retried sends
+	txnPrefix  string
+	txnCounter uint64
+
+	maxRetries int
+
+	ctx context.Context
+}
+
+func NewMatrixNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	if len(conf.MsgType) == 0 {
+		conf.MsgType = msgTypeNotice
+	}
+	maxRetries := 3
+	if conf.MaxRetries != nil {
+		maxRetries = *conf.MaxRetries
+		if maxRetries < 0 {
+			errs.Addf("max_retries", "must not be negative")
+		}
+	}
+
+	config.Require(&errs, "homeserver_url", conf.HomeserverURL)
+	config.Require(&errs, "access_token", conf.AccessToken)
+	config.Require(&errs, "room_id", conf.RoomID)
+	config.OneOf(&errs, "msg_type", conf.MsgType, msgTypeNotice, msgTypeText)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:     conf,
+		throttler:  notifier.NewThrottler(ctx, throttle),
+		txnPrefix:  fmt.Sprintf("crypto-monitor-%d", time.Now().UnixNano()),
+		maxRetries: maxRetries,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (m *Notifier) Notify(msg, from string, throttle bool) error {
+	return m.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (m *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending matrix notification...")
+	log.Debugf("matrix payload: %v", msg)
+
+	if throttle && !m.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	formatted := strings.ReplaceAll(html.EscapeString(msg), "\n", "<br>")
+	if headline := details.Headline(); len(headline) > 0 {
+		formatted = fmt.Sprintf("<strong>%s</strong><br>%s", html.EscapeString(headline), formatted)
+	}
+	payload := &Message{
+		MsgType:       m.config.MsgType,
+		Body:          msg,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	}
+
+	txnID := fmt.Sprintf("%s-%d", m.txnPrefix, atomic.AddUint64(&m.txnCounter, 1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(m.config.HomeserverURL, "/"), url.PathEscape(m.config.RoomID), url.PathEscape(txnID))
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := m.send(endpoint, payload)
+		if err == nil {
+			log.Info("successfully notified via matrix")
+			return nil
+		}
+		if retryAfter == 0 || attempt >= m.maxRetries {
+			return err
+		}
+
+		log.Warnf("matrix notify fail: %v, retry in %v", err, retryAfter)
+		select {
+		case <-time.After(retryAfter):
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		}
+	}
+}
+
+// send puts the event to the room, it returns how long to wait before retrying
+// if the failure is transient, or zero if retrying won't help.
+func (m *Notifier) send(endpoint string, payload *Message) (time.Duration, error) {
+	request, err := http.NewRequestWithContext(m.ctx, http.MethodPut, endpoint, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Add("content-type", "application/json")
+	request.Header.Add("Authorization", "Bearer "+m.config.AccessToken)
+
+	resp, err := m.httpClient.Do(request)
+	if err != nil {
+		return time.Second, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return 0, nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	result := &ErrorResponse{}
+	_ = json.Unmarshal(body, result)
+	err = fmt.Errorf("%s %s: %s", resp.Status, result.ErrCode, result.Error)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := time.Duration(result.RetryAfterMs) * time.Millisecond
+		if retryAfter <= 0 {
+			retryAfter = 5 * time.Second
+		}
+		return retryAfter, err
+	case resp.StatusCode >= 500:
+		return time.Second, err
+	default:
+		return 0, err
+	}
+}
@@ -0,0 +1,69 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  string
+		requests int32
+	}{
+		{name: "default", requests: 4},
+		{name: "none", retries: `, "max_retries": 0`, requests: 1},
+		{name: "one", retries: `, "max_retries": 1`, requests: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			var txnIDs []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				txnIDs = append(txnIDs, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = fmt.Fprint(w, `{"errcode": "M_LIMIT_EXCEEDED", "error": "Too many requests", "retry_after_ms": 1}`)
+			}))
+			defer server.Close()
+
+			rawConf := fmt.Sprintf(`{"type": "matrix", "homeserver_url": %q, "access_token": "token", "room_id": "!room:example.org"%s}`, server.URL, test.retries)
+			n, err := NewMatrixNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Notify("BTC moved", "test", false); err == nil {
+				t.Error("rate limited notification succeeded")
+			}
+			if requests := atomic.LoadInt32(&requests); requests != test.requests {
+				t.Errorf("sent %d requests, want %d", requests, test.requests)
+			}
+			for _, txnID := range txnIDs {
+				if txnID != txnIDs[0] {
+					t.Errorf("retried with transaction id %s, want %s", txnID, txnIDs[0])
+				}
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	tests := map[string]bool{
+		``:                         true,
+		`, "msg_type": "m.text"`:   true,
+		`, "msg_type": "m.notice"`: true,
+		`, "msg_type": "m.emote"`:  false,
+		`, "max_retries": -1`:      false,
+	}
+	for fields, valid := range tests {
+		rawConf := fmt.Sprintf(`{"type": "matrix", "homeserver_url": "http://localhost", "access_token": "token", "room_id": "!room:example.org"%s}`, fields)
+		if _, err := NewMatrixNotifier(context.Background(), []byte(rawConf)); (err == nil) != valid {
+			t.Errorf("config %q: got error %v, want valid %v", fields, err, valid)
+		}
+	}
+}
@@ -3,15 +3,136 @@ package notifier
 import (
 	"context"
 	"encoding/json"
-	log "github.com/sirupsen/logrus"
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"strings"
 	"sync"
 )
 
+var (
+	// ErrThrottled is returned by notifiers dropping a message because of throttling.
+	ErrThrottled = errors.New("notification throttled")
+	// ErrSilenced is returned for messages held back by quiet hours or silences.
+	ErrSilenced = errors.New("notification silenced")
+	// ErrGrouped is reported for messages queued to be sent along with others of their group,
+	// the outcome of the group is reported once it is sent. Notifiers queueing messages, e.g.
+	// for a digest, report it before queueing and return it too.
+	ErrGrouped = errors.New("notification grouped")
+)
+
+// Notifier delivers messages. Notify returns ErrThrottled if the message was dropped by
+// throttling, ErrGrouped if it was queued to be sent later, or the error which prevented its
+// delivery.
 type Notifier interface {
-	Notify(msg, from string, throttle bool)
+	Notify(msg, from string, throttle bool) error
+}
+
+// StateResolved is the state of the details of an alert which cleared.
+const StateResolved = "resolved"
+
+// Details carries the structured data behind a notification, for notifiers
+// able to render more than the plain text message.
+type Details struct {
+	Strategy string
+	Severity Severity
+	Price    *collector.WindowPrice
+
+	// State is the state of the alert, "firing" or "resolved", empty for plain messages
+	State string
+	// Incident identifies the alert within its strategy across its firing and resolved messages
+	Incident string
+	// Data is what the message was rendered from, for notifiers rendering their own template
+	Data *templates.Data
+	// Alert is the message as a structured alert, for notifiers implementing AlertNotifier
+	Alert *Alert
+
+	// reporter learns the outcome of each notifier the message went through
+	reporter func(notifier string, err error)
 }
 
-type Builder func(ctx context.Context, rawConf json.RawMessage) Notifier
+// WithReporter returns a copy of the details which reports the outcome of each notifier
+// delivering the message to reporter, e.g. to journal the alert.
+func (d *Details) WithReporter(reporter func(notifier string, err error)) *Details {
+	copied := *d
+	copied.reporter = reporter
+	return &copied
+}
+
+// ReportingAs returns a copy of the details which reports every outcome as the one of the
+// notifier name, for the notifiers it wraps to report the outcome of queued messages later.
+func (d *Details) ReportingAs(name string) *Details {
+	if d == nil || d.reporter == nil {
+		return d
+	}
+	copied := *d
+	copied.reporter = func(_ string, err error) {
+		d.reporter(name, err)
+	}
+	return &copied
+}
+
+// Report passes the outcome of a notifier to the reporter of the details, if any.
+func (d *Details) Report(notifier string, err error) {
+	if d != nil && d.reporter != nil {
+		d.reporter(notifier, err)
+	}
+}
+
+// Headline returns a one-line summary of the price change, e.g. "BTC-USDT ▲ 5.2%",
+// or an empty string if there is none.
+func (d *Details) Headline() string {
+	if d.Price == nil {
+		return ""
+	}
+
+	arrow := "▲"
+	if !d.Price.Rising() {
+		arrow = "▼"
+	}
+	headline := fmt.Sprintf("%s %s %v%%", d.Price.SymbolPair(), arrow, d.Price.RelativePriceChange)
+	if d.State == StateResolved {
+		headline = "[RESOLVED] " + headline
+	}
+	return headline
+}
+
+// Title returns the headline of the details, or the first line of msg if there is none.
+func (d *Details) Title(msg string) string {
+	if headline := d.Headline(); len(headline) > 0 {
+		return headline
+	}
+	return strings.TrimSpace(strings.SplitN(msg, "\n", 2)[0])
+}
+
+// DetailedNotifier is implemented by notifiers that can render Details natively,
+// e.g. as chat cards with colors and fields.
+type DetailedNotifier interface {
+	NotifyDetails(msg, from string, throttle bool, details *Details) error
+}
+
+// Waiter is implemented by notifiers doing work in the background, e.g. batching messages
+// or holding a connection. The work ends once the notifier's context is done, Wait blocks
+// until it has, or until ctx is done.
+type Waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Send delivers msg through n, passing details along if n supports them, or as an alert if
+// n is an AlertNotifier.
+func Send(n Notifier, msg, from string, throttle bool, details *Details) error {
+	if an, ok := n.(AlertNotifier); ok && details != nil {
+		return an.NotifyAlert(AlertOf(msg, details), from, throttle)
+	}
+	if dn, ok := n.(DetailedNotifier); ok && details != nil {
+		return dn.NotifyDetails(msg, from, throttle, details)
+	}
+	return n.Notify(msg, from, throttle)
+}
+
+type Builder func(ctx context.Context, rawConf json.RawMessage) (Notifier, error)
 
 var registry Registry
 
@@ -23,24 +144,30 @@ func (r *Registry) Register(tpy string, builder Builder) {
 	r.notifiers.Store(tpy, builder)
 }
 
-func (r *Registry) GetNotifier(ctx context.Context, rawConf json.RawMessage) Notifier {
-	type collectorTypeConf struct {
-		Type string `json:"type"`
-	}
-
-	typeConf := &collectorTypeConf{}
+func (r *Registry) GetNotifier(ctx context.Context, rawConf json.RawMessage) (Notifier, error) {
+	typeConf := &config.Component{}
 	if err := json.Unmarshal(rawConf, typeConf); err != nil {
-		log.Panic("load config fail", err)
+		return nil, config.Errors{{Err: err}}
 	}
 
 	builder, exist := r.notifiers.Load(typeConf.Type)
 	if !exist {
-		return nil
+		return nil, config.Errors{{Path: "type", Err: config.UnknownType(typeConf.Type, r.Types())}}
 	}
 
 	return builder.(Builder)(ctx, rawConf)
 }
 
+// Types lists the registered notifier types.
+func (r *Registry) Types() []string {
+	var types []string
+	r.notifiers.Range(func(key, _ any) bool {
+		types = append(types, key.(string))
+		return true
+	})
+	return types
+}
+
 func GetRegistry() *Registry {
 	return &registry
 }
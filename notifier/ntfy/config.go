@@ -0,0 +1,15 @@
+package ntfy
+
+type Config struct {
+	Timeout  string `json:"timeout"`
+	Throttle string `json:"throttle"`
+
+	// TopicURL is the full topic URL, e.g. https://ntfy.example.com/crypto-alerts
+	TopicURL string `json:"topic_url"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Priorities maps a severity name to a ntfy priority from 1 (min) to 5 (max).
+	Priorities map[string]int `json:"priorities"`
+}
@@ -0,0 +1,9 @@
+package ntfy
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("ntfy", NewNtfyNotifier)
+}
@@ -0,0 +1,112 @@
+package ntfy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var defaultPriorities = map[notifier.Severity]int{
+	notifier.SeverityInfo:     3,
+	notifier.SeverityWarning:  4,
+	notifier.SeverityCritical: 5,
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewNtfyNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	config.Require(&errs, "topic_url", conf.TopicURL)
+	notifier.CheckSeverityKeys(&errs, "priorities", conf.Priorities)
+	notifier.CheckSeverityRange(&errs, "priorities", conf.Priorities, 1, 5)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (n *Notifier) Notify(msg, from string, throttle bool) error {
+	return n.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (n *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending ntfy notification...")
+	log.Debugf("ntfy payload: %v", msg)
+
+	if throttle && !n.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	request, err := http.NewRequestWithContext(n.ctx, http.MethodPost, n.config.TopicURL, strings.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	// header values must be ASCII, ntfy decodes RFC 2047 encoded titles
+	request.Header.Set("Title", mime.BEncoding.Encode("utf-8", details.Title(msg)))
+	request.Header.Set("Priority", strconv.Itoa(n.priorityOf(details.Severity)))
+	if price := details.Price; price != nil {
+		if price.Rising() {
+			request.Header.Set("Tags", "chart_with_upwards_trend")
+		} else {
+			request.Header.Set("Tags", "chart_with_downwards_trend")
+		}
+	}
+	if len(n.config.Token) > 0 {
+		request.Header.Set("Authorization", "Bearer "+n.config.Token)
+	} else if len(n.config.Username) > 0 {
+		request.SetBasicAuth(n.config.Username, n.config.Password)
+	}
+
+	resp, err := n.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, errorMsg)
+	}
+	log.Info("successfully notified via ntfy")
+	return nil
+}
+
+func (n *Notifier) priorityOf(severity notifier.Severity) int {
+	if priority, ok := n.config.Priorities[severity.String()]; ok {
+		return priority
+	}
+	return defaultPriorities[severity]
+}
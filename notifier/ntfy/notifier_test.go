@@ -0,0 +1,91 @@
+package ntfy
+
+import (
+	"context"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotifyDetails(t *testing.T) {
+	tests := []struct {
+		severity notifier.Severity
+		price    *collector.WindowPrice
+		priority string
+		tags     string
+	}{
+		{severity: notifier.SeverityInfo, priority: "3"},
+		{severity: notifier.SeverityWarning, priority: "1", price: &collector.WindowPrice{Symbol1: "BTC", Symbol2: "USDT", AbsolutePriceChange: 100}, tags: "chart_with_upwards_trend"},
+		{severity: notifier.SeverityCritical, priority: "5", price: &collector.WindowPrice{Symbol1: "BTC", Symbol2: "USDT", AbsolutePriceChange: -100}, tags: "chart_with_downwards_trend"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.severity.String(), func(t *testing.T) {
+			var request *http.Request
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				raw, _ := io.ReadAll(r.Body)
+				request, body = r, string(raw)
+			}))
+			defer server.Close()
+
+			rawConf := fmt.Sprintf(`{"type": "ntfy", "topic_url": %q, "token": "secret", "priorities": {"warning": 1}}`, server.URL+"/alerts")
+			n, err := NewNtfyNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			details := &notifier.Details{Severity: test.severity, Price: test.price}
+			if err := notifier.Send(n, "价格波动\nBTC", "test", false, details); err != nil {
+				t.Fatal(err)
+			}
+
+			title, _ := new(mime.WordDecoder).DecodeHeader(request.Header.Get("Title"))
+			if request.URL.Path != "/alerts" || body != "价格波动\nBTC" || title != details.Title(body) {
+				t.Errorf("unexpected request to %s, title %q, body %q", request.URL.Path, title, body)
+			}
+			if priority := request.Header.Get("Priority"); priority != test.priority {
+				t.Errorf("priority %s, want %s", priority, test.priority)
+			}
+			if tags := request.Header.Get("Tags"); tags != test.tags {
+				t.Errorf("tags %q, want %q", tags, test.tags)
+			}
+			if auth := request.Header.Get("Authorization"); auth != "Bearer secret" {
+				t.Errorf("authorization %q", auth)
+			}
+		})
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	n, err := NewNtfyNotifier(context.Background(), []byte(fmt.Sprintf(`{"type": "ntfy", "topic_url": %q}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify("alert", "test", false); err == nil {
+		t.Error("failed request reported as sent")
+	}
+}
+
+func TestPriorityRange(t *testing.T) {
+	tests := map[string]bool{
+		`{"warning": 1, "critical": 5}`: true,
+		`{"warning": 0}`:                false,
+		`{"critical": 6}`:               false,
+	}
+	for priorities, valid := range tests {
+		rawConf := fmt.Sprintf(`{"type": "ntfy", "topic_url": "http://localhost/alerts", "priorities": %s}`, priorities)
+		if _, err := NewNtfyNotifier(context.Background(), []byte(rawConf)); (err == nil) != valid {
+			t.Errorf("priorities %s: got error %v, want valid %v", priorities, err, valid)
+		}
+	}
+}
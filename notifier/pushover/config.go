@@ -0,0 +1,21 @@
+package pushover
+
+type Config struct {
+	Timeout  string `json:"timeout"`
+	Throttle string `json:"throttle"`
+
+	APIURL   string `json:"api_url"`
+	AppToken string `json:"app_token"`
+	UserKey  string `json:"user_key"`
+	Device   string `json:"device"`
+
+	// Priorities maps a severity name to a pushover priority from -2 to 2,
+	// Sounds maps it to a notification sound.
+	Priorities map[string]int    `json:"priorities"`
+	Sounds     map[string]string `json:"sounds"`
+
+	// Retry and Expire control how emergency (priority 2) notifications repeat until acknowledged,
+	// every 30s at the most often and for 3h at the longest.
+	Retry  string `json:"retry"`
+	Expire string `json:"expire"`
+}
@@ -0,0 +1,9 @@
+package pushover
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("pushover", NewPushoverNotifier)
+}
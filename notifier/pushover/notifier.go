@@ -0,0 +1,150 @@
+package pushover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAPIURL     = "https://api.pushover.net/1/messages.json"
+	priorityEmergency = 2
+
+	minRetry  = 30 * time.Second
+	maxExpire = 3 * time.Hour
+)
+
+var defaultPriorities = map[notifier.Severity]int{
+	notifier.SeverityInfo:     -1,
+	notifier.SeverityWarning:  0,
+	notifier.SeverityCritical: priorityEmergency,
+}
+
+type Response struct {
+	Status int      `json:"status"`
+	Errors []string `json:"errors"`
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	retry  time.Duration
+	expire time.Duration
+
+	ctx context.Context
+}
+
+func NewPushoverNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+	retry := config.ParseDuration(&errs, "retry", conf.Retry, time.Minute)
+	expire := config.ParseDuration(&errs, "expire", conf.Expire, 30*time.Minute)
+
+	if len(conf.APIURL) == 0 {
+		conf.APIURL = defaultAPIURL
+	}
+
+	config.Require(&errs, "app_token", conf.AppToken)
+	config.Require(&errs, "user_key", conf.UserKey)
+	notifier.CheckSeverityKeys(&errs, "priorities", conf.Priorities)
+	notifier.CheckSeverityRange(&errs, "priorities", conf.Priorities, -2, priorityEmergency)
+	// pushover rejects emergency notifications retried more often or expiring later than this
+	if retry < minRetry {
+		errs.Addf("retry", "must be at least %v", minRetry)
+	}
+	if expire <= 0 || expire > maxExpire {
+		errs.Addf("expire", "must be positive and at most %v", maxExpire)
+	}
+	notifier.CheckSeverityKeys(&errs, "sounds", conf.Sounds)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		retry:     retry,
+		expire:    expire,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (p *Notifier) Notify(msg, from string, throttle bool) error {
+	return p.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (p *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending pushover notification...")
+	log.Debugf("pushover payload: %v", msg)
+
+	if throttle && !p.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	priority := p.priorityOf(details.Severity)
+	form := url.Values{}
+	form.Set("token", p.config.AppToken)
+	form.Set("user", p.config.UserKey)
+	form.Set("title", details.Title(msg))
+	form.Set("message", msg)
+	form.Set("priority", strconv.Itoa(priority))
+	if len(p.config.Device) > 0 {
+		form.Set("device", p.config.Device)
+	}
+	if sound, ok := p.config.Sounds[details.Severity.String()]; ok {
+		form.Set("sound", sound)
+	}
+	if priority == priorityEmergency {
+		form.Set("retry", strconv.Itoa(int(p.retry.Seconds())))
+		form.Set("expire", strconv.Itoa(int(p.expire.Seconds())))
+	}
+
+	request, err := http.NewRequestWithContext(p.ctx, http.MethodPost, p.config.APIURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result := &Response{}
+	if err := json.Unmarshal(body, result); err != nil || resp.StatusCode != http.StatusOK || result.Status != 1 {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	log.Info("successfully notified via pushover")
+	return nil
+}
+
+func (p *Notifier) priorityOf(severity notifier.Severity) int {
+	if priority, ok := p.config.Priorities[severity.String()]; ok {
+		return priority
+	}
+	return defaultPriorities[severity]
+}
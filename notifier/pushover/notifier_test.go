@@ -0,0 +1,87 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNotifyDetails(t *testing.T) {
+	tests := []struct {
+		severity notifier.Severity
+		want     url.Values
+	}{
+		{severity: notifier.SeverityInfo, want: url.Values{"priority": {"-1"}}},
+		{severity: notifier.SeverityWarning, want: url.Values{"priority": {"0"}, "sound": {"cashregister"}}},
+		{severity: notifier.SeverityCritical, want: url.Values{"priority": {"2"}, "retry": {"30"}, "expire": {"600"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.severity.String(), func(t *testing.T) {
+			var form url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				form = r.PostForm
+				fmt.Fprint(w, `{"status": 1}`)
+			}))
+			defer server.Close()
+
+			rawConf := fmt.Sprintf(`{"type": "pushover", "api_url": %q, "app_token": "app", "user_key": "user",
+				"sounds": {"warning": "cashregister"}, "retry": "30s", "expire": "10m"}`, server.URL)
+			n, err := NewPushoverNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := notifier.Send(n, "价格波动\nBTC", "test", false, &notifier.Details{Severity: test.severity}); err != nil {
+				t.Fatal(err)
+			}
+
+			test.want.Set("token", "app")
+			test.want.Set("user", "user")
+			test.want.Set("title", "价格波动")
+			test.want.Set("message", "价格波动\nBTC")
+			if form.Encode() != test.want.Encode() {
+				t.Errorf("form %v, want %v", form, test.want)
+			}
+		})
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status": 0, "errors": ["user identifier is invalid"]}`)
+	}))
+	defer server.Close()
+
+	rawConf := fmt.Sprintf(`{"type": "pushover", "api_url": %q, "app_token": "app", "user_key": "user"}`, server.URL)
+	n, err := NewPushoverNotifier(context.Background(), []byte(rawConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify("alert", "test", false); err == nil {
+		t.Error("failed request reported as sent")
+	}
+}
+
+func TestConfigRanges(t *testing.T) {
+	tests := map[string]bool{
+		``:                                 true,
+		`, "retry": "30s", "expire": "3h"`: true,
+		`, "retry": "10s"`:                 false,
+		`, "expire": "4h"`:                 false,
+		`, "expire": "0s"`:                 false,
+		`, "priorities": {"critical": 3}`:  false,
+		`, "priorities": {"info": -2}`:     true,
+	}
+	for fields, valid := range tests {
+		rawConf := fmt.Sprintf(`{"type": "pushover", "app_token": "app", "user_key": "user"%s}`, fields)
+		if _, err := NewPushoverNotifier(context.Background(), []byte(rawConf)); (err == nil) != valid {
+			t.Errorf("config %q: got error %v, want valid %v", fields, err, valid)
+		}
+	}
+}
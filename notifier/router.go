@@ -0,0 +1,238 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type route struct {
+	strategies map[string]struct{}
+	pairs      map[string]struct{}
+	severities map[Severity]struct{}
+	timeRange  *timeRange
+
+	notifiers []string
+	proceed   bool
+}
+
+func (r *route) match(details *Details, now time.Time) bool {
+	if len(r.strategies) > 0 {
+		if _, ok := r.strategies[strings.ToLower(details.Strategy)]; !ok {
+			return false
+		}
+	}
+	if len(r.pairs) > 0 {
+		if details.Price == nil {
+			return false
+		}
+		if _, ok := r.pairs[strings.ToLower(details.Price.SymbolPair())]; !ok {
+			return false
+		}
+	}
+	if len(r.severities) > 0 {
+		if _, ok := r.severities[details.Severity]; !ok {
+			return false
+		}
+	}
+	if r.timeRange != nil && !r.timeRange.contains(now) {
+		return false
+	}
+	return true
+}
+
+type timeRange struct {
+	from, to time.Duration
+	location *time.Location
+}
+
+func (t *timeRange) contains(now time.Time) bool {
+	now = now.In(t.location)
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	if t.from <= t.to {
+		return offset >= t.from && offset < t.to
+	}
+	// the range wraps around midnight
+	return offset >= t.from || offset < t.to
+}
+
+// Router is a Notifier dispatching each notification to the notifiers of the matching routes.
+type Router struct {
+	routes    []*route
+	defaults  []string
+	notifiers map[string]Notifier
+}
+
+// NewRouter builds a router from conf, notifiers are looked up by name. Without default
+// notifiers in conf, the notifiers of names are the default ones in that order, e.g. every
+// notifier in config order.
+func NewRouter(conf *config.Routing, names []string, notifiers map[string]Notifier) (*Router, error) {
+	errs := config.Errors{}
+	lookup := func(field string, names []string) []string {
+		result := make([]string, 0, len(names))
+		for i, name := range names {
+			if _, ok := notifiers[name]; !ok {
+				errs.Addf(config.Index(field, i), "unknown notifier %q", name)
+				continue
+			}
+			result = append(result, name)
+		}
+		return result
+	}
+
+	router := &Router{notifiers: notifiers}
+
+	if len(conf.Default) > 0 {
+		router.defaults = lookup("default", conf.Default)
+	} else {
+		for _, name := range names {
+			if _, ok := notifiers[name]; ok {
+				router.defaults = append(router.defaults, name)
+			}
+		}
+	}
+
+	for i, routeConf := range conf.Routes {
+		path := config.Index("routes", i)
+		r := &route{
+			strategies: toSet(routeConf.Strategies),
+			pairs:      toSet(routeConf.Pairs),
+			severities: make(map[Severity]struct{}, len(routeConf.Severities)),
+			notifiers:  lookup(config.JoinPath(path, "notifiers"), routeConf.Notifiers),
+			proceed:    routeConf.Continue,
+		}
+		if len(routeConf.Notifiers) == 0 {
+			errs.Addf(config.JoinPath(path, "notifiers"), "at least one notifier is required")
+		}
+
+		for j, name := range routeConf.Severities {
+			severity, err := ParseSeverity(name)
+			if err != nil {
+				errs.Add(config.Index(config.JoinPath(path, "severities"), j), err)
+				continue
+			}
+			r.severities[severity] = struct{}{}
+		}
+
+		if routeConf.Time != nil {
+			tr, err := parseTimeRange(routeConf.Time)
+			if err != nil {
+				errs.Add(config.JoinPath(path, "time"), err)
+			}
+			r.timeRange = tr
+		}
+
+		router.routes = append(router.routes, r)
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+func (r *Router) Notify(msg, from string, throttle bool) error {
+	return r.NotifyDetails(msg, from, throttle, &Details{})
+}
+
+// NotifyDetails sends the message to the notifiers of the matching routes, once to each even
+// if several routes name it. It only fails if none of them delivered it, returning the first error.
+func (r *Router) NotifyDetails(msg, from string, throttle bool, details *Details) error {
+	now := time.Now()
+	matched := false
+	result := &routeResult{sent: make(map[string]bool)}
+
+	for i, rt := range r.routes {
+		if !rt.match(details, now) {
+			continue
+		}
+
+		log.Debugf("notification from %s matched route %d", from, i)
+		matched = true
+		for _, name := range rt.notifiers {
+			r.send(result, name, msg, from, throttle, details)
+		}
+		if !rt.proceed {
+			return result.err()
+		}
+	}
+
+	if !matched {
+		log.Debugf("notification from %s matched no route, using default", from)
+		for _, name := range r.defaults {
+			r.send(result, name, msg, from, throttle, details)
+		}
+	}
+	return result.err()
+}
+
+func (r *Router) send(result *routeResult, name, msg, from string, throttle bool, details *Details) {
+	if result.sent[name] {
+		return
+	}
+	result.sent[name] = true
+	result.add(Send(r.notifiers[name], msg, from, throttle, details))
+}
+
+type routeResult struct {
+	sent      map[string]bool
+	delivered bool
+	firstErr  error
+}
+
+func (r *routeResult) add(err error) {
+	if err == nil {
+		r.delivered = true
+	} else if r.firstErr == nil {
+		r.firstErr = err
+	}
+}
+
+func (r *routeResult) err() error {
+	if r.delivered {
+		return nil
+	}
+	return r.firstErr
+}
+
+func parseTimeRange(conf *config.TimeRange) (*timeRange, error) {
+	errs := config.Errors{}
+
+	location := time.Local
+	if len(conf.Timezone) > 0 {
+		loc, err := time.LoadLocation(conf.Timezone)
+		if err != nil {
+			errs.Addf("timezone", "unknown timezone %q", conf.Timezone)
+		} else {
+			location = loc
+		}
+	}
+
+	from, err := parseTimeOfDay(conf.From)
+	errs.Add("from", err)
+	to, err := parseTimeOfDay(conf.To)
+	errs.Add("to", err)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return &timeRange{from: from, to: to, location: location}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expecting HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"github.com/azraeljack/crypto-monitor/config"
+	"reflect"
+	"testing"
+)
+
+type recorder struct {
+	name string
+	log  *[]string
+}
+
+func (r *recorder) Notify(msg, from string, throttle bool) error {
+	*r.log = append(*r.log, r.name)
+	return nil
+}
+
+func TestRouter(t *testing.T) {
+	var log []string
+	notifiers := map[string]Notifier{}
+	names := []string{"slack", "email", "pager"}
+	for _, name := range names {
+		notifiers[name] = &recorder{name: name, log: &log}
+	}
+
+	tests := []struct {
+		name    string
+		routing *config.Routing
+		details *Details
+		want    []string
+	}{
+		{
+			name:    "default in order",
+			routing: &config.Routing{Default: []string{"slack", "pager"}},
+			details: &Details{},
+			want:    []string{"slack", "pager"},
+		},
+		{
+			name:    "every notifier by default in config order",
+			routing: &config.Routing{},
+			details: &Details{},
+			want:    []string{"slack", "email", "pager"},
+		},
+		{
+			name: "continue dedupes",
+			routing: &config.Routing{Routes: []*config.Route{
+				{Strategies: []string{"btc"}, Notifiers: []string{"slack", "email"}, Continue: true},
+				{Severities: []string{"critical"}, Notifiers: []string{"email", "pager"}},
+			}},
+			details: &Details{Strategy: "btc", Severity: SeverityCritical},
+			want:    []string{"slack", "email", "pager"},
+		},
+		{
+			name: "first match stops",
+			routing: &config.Routing{Default: []string{"slack"}, Routes: []*config.Route{
+				{Strategies: []string{"btc"}, Notifiers: []string{"email"}},
+				{Strategies: []string{"btc"}, Notifiers: []string{"pager"}},
+			}},
+			details: &Details{Strategy: "btc"},
+			want:    []string{"email"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router, err := NewRouter(test.routing, names, notifiers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			log = nil
+			if err := router.NotifyDetails("msg", "test", false, test.details); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(log, test.want) {
+				t.Errorf("notified %v, want %v", log, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"strings"
+)
+
+// Severity ranks how urgent a notification is, notifiers use it to pick
+// mentions, priorities or colors.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "warning", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(n, name) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity: %s", name)
+}
+
+// CheckSeverityKeys records an error in errs for each key of a per-severity config map that isn't a severity name.
+func CheckSeverityKeys[V any](errs *config.Errors, field string, m map[string]V) {
+	for key := range m {
+		if _, err := ParseSeverity(key); err != nil {
+			errs.Add(config.JoinPath(field, key), err)
+		}
+	}
+}
+
+// CheckSeverityRange records an error in errs for each value of a per-severity config map
+// outside [min, max], e.g. a priority the service doesn't know.
+func CheckSeverityRange(errs *config.Errors, field string, m map[string]int, min, max int) {
+	for key, value := range m {
+		if value < min || value > max {
+			errs.Addf(config.JoinPath(field, key), "must be from %d to %d", min, max)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package slack
+
+type Config struct {
+	Timeout    string `json:"timeout"`
+	WebhookURL string `json:"webhook_url"`
+	Throttle   string `json:"throttle"`
+	Channel    string `json:"channel"`
+	Username   string `json:"username"`
+	IconEmoji  string `json:"icon_emoji"`
+}
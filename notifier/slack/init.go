@@ -0,0 +1,9 @@
+package slack
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("slack", NewSlackNotifier)
+}
@@ -0,0 +1,156 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	colorRising  = "#2eb886"
+	colorFalling = "#e01e5a"
+
+	// maxSectionText is the most characters slack accepts in the text of a section block
+	maxSectionText = 3000
+)
+
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type Block struct {
+	Type     string  `json:"type"`
+	Text     *Text   `json:"text,omitempty"`
+	Fields   []*Text `json:"fields,omitempty"`
+	Elements []*Text `json:"elements,omitempty"`
+}
+
+type Attachment struct {
+	Color  string   `json:"color"`
+	Blocks []*Block `json:"blocks"`
+}
+
+type Message struct {
+	Text        string        `json:"text"`
+	Channel     string        `json:"channel,omitempty"`
+	Username    string        `json:"username,omitempty"`
+	IconEmoji   string        `json:"icon_emoji,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+func (m *Message) ToJSON() []byte {
+	raw, _ := json.Marshal(m)
+	return raw
+}
+
+type Notifier struct {
+	config     *Config
+	httpClient *http.Client
+
+	throttler *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewSlackNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	config.Require(&errs, "webhook_url", conf.WebhookURL)
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:    conf,
+		throttler: notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (s *Notifier) Notify(msg, from string, throttle bool) error {
+	return s.NotifyDetails(msg, from, throttle, &notifier.Details{})
+}
+
+func (s *Notifier) NotifyDetails(msg, from string, throttle bool, details *notifier.Details) error {
+	log.Info("sending slack notification...")
+	log.Debugf("slack payload: %v", msg)
+
+	if throttle && !s.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	payload := &Message{
+		Text:      msg,
+		Channel:   s.config.Channel,
+		Username:  s.config.Username,
+		IconEmoji: s.config.IconEmoji,
+	}
+	if price := details.Price; price != nil {
+		color := colorRising
+		if !price.Rising() {
+			color = colorFalling
+		}
+		payload.Text = details.Headline()
+		payload.Attachments = []*Attachment{{
+			Color: color,
+			Blocks: []*Block{
+				{Type: "section", Text: &Text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*", payload.Text)}},
+				{Type: "section", Text: &Text{Type: "plain_text", Text: truncate(msg, maxSectionText)}},
+				{Type: "section", Fields: []*Text{
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Price*\n%v", price.ClosePrice)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Change*\n%v (%v%%)", price.AbsolutePriceChange, price.RelativePriceChange)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*High / Low*\n%v / %v", price.HighPrice, price.LowPrice)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*Order count*\n%v", price.OrderCount)},
+				}},
+				{Type: "context", Elements: []*Text{{Type: "mrkdwn", Text: from}}},
+			},
+		}}
+	}
+
+	request, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.config.WebhookURL, bytes.NewReader(payload.ToJSON()))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("content-type", "application/json")
+
+	resp, err := s.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, errorMsg)
+	}
+	log.Info("successfully notified via slack")
+	return nil
+}
+
+// truncate cuts text to at most limit characters, slack rejects longer section texts.
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
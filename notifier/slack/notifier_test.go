@@ -0,0 +1,51 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotifyDetailsKeepsMessage(t *testing.T) {
+	message := &Message{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(message)
+	}))
+	defer server.Close()
+
+	n, err := NewSlackNotifier(context.Background(), []byte(fmt.Sprintf(`{"type": "slack", "webhook_url": %q}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	price := &collector.WindowPrice{Symbol1: "BTC", Symbol2: "USDT", OpenPrice: 100, ClosePrice: 105, AbsolutePriceChange: 5, RelativePriceChange: 5}
+	msg := "发现价格波动：\n- 交易对：BTC - USDT"
+	if err := n.(*Notifier).NotifyDetails(msg, "test", false, &notifier.Details{Price: price}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(message.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(message.Attachments))
+	}
+	blocks := message.Attachments[0].Blocks
+	if len(blocks) < 2 || blocks[1].Text == nil || blocks[1].Text.Text != msg {
+		t.Errorf("blocks %+v don't carry the message", blocks)
+	}
+	if !strings.Contains(message.Text, "BTC") {
+		t.Errorf("text %q, want the headline", message.Text)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("价格波动", 10); got != "价格波动" {
+		t.Errorf("truncate kept %q", got)
+	}
+	if got := truncate("价格波动", 3); got != "价格…" {
+		t.Errorf("truncate cut to %q", got)
+	}
+}
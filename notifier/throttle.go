@@ -0,0 +1,30 @@
+package notifier
+
+import (
+	"context"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/status"
+	"time"
+)
+
+// Throttler suppresses repeated notifications from the same source within a time window.
+// The windows are kept in the state store of the context, named after the notifier, so
+// they survive restarts and reloads if the store is persisted.
+type Throttler struct {
+	window time.Duration
+
+	lastNotified *state.Cache
+}
+
+func NewThrottler(ctx context.Context, window time.Duration) *Throttler {
+	return &Throttler{
+		window:       window,
+		lastNotified: state.FromContext(ctx).Cache("throttle/" + status.FromContext(ctx).Name()),
+	}
+}
+
+// Allow reports whether a notification from the given source may be sent now,
+// and records the attempt if so.
+func (t *Throttler) Allow(from string) bool {
+	return t.lastNotified.Acquire(from, t.window)
+}
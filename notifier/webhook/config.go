@@ -0,0 +1,14 @@
+package webhook
+
+type Config struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	Headers         map[string]string `json:"headers"`
+	Format          string            `json:"format"`
+	Body            string            `json:"body"`
+	Form            map[string]string `json:"form"`
+	Secret          string            `json:"secret"`
+	SignatureHeader string            `json:"signature_header"`
+	Timeout         string            `json:"timeout"`
+	Throttle        string            `json:"throttle"`
+}
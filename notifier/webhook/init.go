@@ -0,0 +1,9 @@
+package webhook
+
+import (
+	"github.com/azraeljack/crypto-monitor/notifier"
+)
+
+func init() {
+	notifier.GetRegistry().Register("webhook", NewWebhookNotifier)
+}
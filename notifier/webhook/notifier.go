@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	formatJSON = "json"
+	formatForm = "form"
+	formatText = "text"
+)
+
+type Notifier struct {
+	format          string
+	method          *template.Template
+	url             *template.Template
+	headers         map[string]*template.Template
+	body            *template.Template
+	form            map[string]*template.Template
+	secret          []byte
+	signatureHeader string
+
+	httpClient *http.Client
+	throttler  *notifier.Throttler
+
+	ctx context.Context
+}
+
+func NewWebhookNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
+	conf := &Config{}
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
+	}
+
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+
+	if len(conf.Method) == 0 {
+		conf.Method = http.MethodPost
+	}
+	if len(conf.Format) == 0 {
+		conf.Format = formatJSON
+	}
+	if len(conf.Body) == 0 && conf.Format == formatJSON {
+		conf.Body = defaultJSONBody
+	}
+	if len(conf.SignatureHeader) == 0 {
+		conf.SignatureHeader = "X-Signature-256"
+	}
+
+	config.Require(&errs, "url", conf.URL)
+	config.OneOf(&errs, "format", conf.Format, formatJSON, formatText, formatForm)
+
+	parseField := func(field, text string) *template.Template {
+		tmpl, err := parse(field, text)
+		if err != nil {
+			errs.Addf(field, "invalid template: %v", err)
+		}
+		return tmpl
+	}
+
+	n := &Notifier{
+		format:          conf.Format,
+		method:          parseField("method", conf.Method),
+		url:             parseField("url", conf.URL),
+		body:            parseField("body", conf.Body),
+		headers:         make(map[string]*template.Template, len(conf.Headers)),
+		form:            make(map[string]*template.Template, len(conf.Form)),
+		secret:          []byte(conf.Secret),
+		signatureHeader: conf.SignatureHeader,
+		throttler:       notifier.NewThrottler(ctx, throttle),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: ctx,
+	}
+	for key, value := range conf.Headers {
+		n.headers[key] = parseField(config.JoinPath("headers", key), value)
+	}
+	for key, value := range conf.Form {
+		n.form[key] = parseField(config.JoinPath("form", key), value)
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (w *Notifier) Notify(msg, from string, throttle bool) error {
+	return w.NotifyAlert(notifier.AlertOf(msg, nil), from, throttle)
+}
+
+func (w *Notifier) NotifyAlert(alert *notifier.Alert, from string, throttle bool) error {
+	log.Info("sending webhook notification...")
+	log.Debugf("webhook payload: %v", alert.Text())
+
+	if throttle && !w.throttler.Allow(from) {
+		return notifier.ErrThrottled
+	}
+
+	request, err := w.buildRequest(newData(alert, from))
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errorMsg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s", resp.Status, errorMsg)
+	}
+	log.Info("successfully notified via webhook")
+	return nil
+}
+
+func (w *Notifier) buildRequest(data *Data) (*http.Request, error) {
+	method, err := render(w.method, data)
+	if err != nil {
+		return nil, fmt.Errorf("render method: %w", err)
+	}
+	target, err := render(w.url, data)
+	if err != nil {
+		return nil, fmt.Errorf("render url: %w", err)
+	}
+
+	body, contentType, err := w.renderBody(data)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(w.ctx, strings.ToUpper(strings.TrimSpace(method)), strings.TrimSpace(target), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("content-type", contentType)
+
+	for key, tmpl := range w.headers {
+		value, err := render(tmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("render header %s: %w", key, err)
+		}
+		request.Header.Set(key, value)
+	}
+
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write([]byte(body))
+		request.Header.Set(w.signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	return request, nil
+}
+
+func (w *Notifier) renderBody(data *Data) (string, string, error) {
+	switch w.format {
+	case formatForm:
+		values := url.Values{}
+		for key, tmpl := range w.form {
+			value, err := render(tmpl, data)
+			if err != nil {
+				return "", "", fmt.Errorf("render form field %s: %w", key, err)
+			}
+			values.Set(key, value)
+		}
+		return values.Encode(), "application/x-www-form-urlencoded", nil
+	case formatText:
+		body, err := render(w.body, data)
+		if err != nil {
+			return "", "", fmt.Errorf("render body: %w", err)
+		}
+		return body, "text/plain; charset=utf-8", nil
+	default:
+		body, err := render(w.body, data)
+		if err != nil {
+			return "", "", fmt.Errorf("render body: %w", err)
+		}
+		if !json.Valid([]byte(body)) {
+			return "", "", fmt.Errorf("rendered body is not valid json: %s", body)
+		}
+		return body, "application/json", nil
+	}
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testAlert() *notifier.Alert {
+	return &notifier.Alert{
+		Title:    "BTC-USDT ▲ 5%",
+		Body:     "BTC-USDT rose 5%",
+		Severity: notifier.SeverityCritical,
+		State:    "firing",
+		Strategy: "btc",
+		Incident: "BTC-USDT",
+		Labels:   map[string]string{notifier.LabelPair: "BTC-USDT"},
+		Fields:   []*notifier.Field{{Name: "Price", Value: "105"}},
+		Price:    &collector.WindowPrice{Symbol1: "BTC", Symbol2: "USDT", ClosePrice: 105},
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		conf        string
+		alert       *notifier.Alert
+		body        string
+		contentType string
+		invalid     bool
+	}{
+		{
+			name:        "text",
+			conf:        `"format": "text", "body": "{{.Severity}} {{.Strategy}} {{.State}} {{.Incident}} {{index .Labels \"pair\"}}{{range .Fields}} {{.Name}}={{.Value}}{{end}}"`,
+			alert:       testAlert(),
+			body:        "critical btc firing BTC-USDT BTC-USDT Price=105",
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:        "empty text body",
+			conf:        `"format": "text"`,
+			alert:       testAlert(),
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:        "plain message",
+			conf:        `"format": "text", "body": "{{.Severity}}|{{.State}}|{{.Message}}|{{.Price}}"`,
+			alert:       notifier.AlertOf("hello", nil),
+			body:        "info||hello|<nil>",
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:        "form",
+			conf:        `"format": "form", "form": {"incident": "{{.Incident}}", "state": "{{.State}}"}`,
+			alert:       testAlert(),
+			body:        "incident=BTC-USDT&state=firing",
+			contentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:    "invalid json",
+			conf:    `"body": "{{.Message}}"`,
+			alert:   testAlert(),
+			invalid: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rawConf := fmt.Sprintf(`{"type": "webhook", "url": "http://localhost/{{.Strategy}}", %s}`, test.conf)
+			n, err := NewWebhookNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request, err := n.(*Notifier).buildRequest(newData(test.alert, "test"))
+			if test.invalid {
+				if err == nil {
+					t.Error("rendered an invalid json body")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(request.Body)
+			if string(body) != test.body {
+				t.Errorf("rendered body %q, want %q", body, test.body)
+			}
+			if contentType := request.Header.Get("content-type"); contentType != test.contentType {
+				t.Errorf("content type %q, want %q", contentType, test.contentType)
+			}
+			if test.alert.Strategy == "btc" && request.URL.Path != "/btc" {
+				t.Errorf("rendered url %v, want the strategy in its path", request.URL)
+			}
+		})
+	}
+}
+
+func TestDefaultBody(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Signature-256")
+	}))
+	defer server.Close()
+
+	rawConf := fmt.Sprintf(`{"type": "webhook", "url": %q, "secret": "secret"}`, server.URL)
+	n, err := NewWebhookNotifier(context.Background(), []byte(rawConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.SendAlert(n, testAlert(), "test", false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got := struct {
+		Message  string            `json:"message"`
+		Severity string            `json:"severity"`
+		Strategy string            `json:"strategy"`
+		State    string            `json:"state"`
+		Incident string            `json:"incident"`
+		Labels   map[string]string `json:"labels"`
+		Price    *struct {
+			ClosePrice float64 `json:"close_price"`
+		} `json:"price"`
+	}{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid default body %s: %v", body, err)
+	}
+	if got.Message != "BTC-USDT rose 5%" || got.Severity != "critical" || got.Strategy != "btc" || got.State != "firing" ||
+		got.Incident != "BTC-USDT" || got.Labels[notifier.LabelPair] != "BTC-USDT" || got.Price == nil || got.Price.ClosePrice != 105 {
+		t.Errorf("default body %s is missing alert fields", body)
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature %q, want %q", signature, want)
+	}
+}
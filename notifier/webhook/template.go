@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"text/template"
+	"time"
+)
+
+const defaultJSONBody = `{"message": {{json .Message}}, "from": {{json .From}}, "time": {{json .Time}}, "severity": {{json .Severity}}, "strategy": {{json .Strategy}}, "state": {{json .State}}, "incident": {{json .Incident}}, "labels": {{json .Labels}}, "price": {{json .Price}}}`
+
+// Data is what the method, URL, header and body templates are rendered with.
+type Data struct {
+	Message string
+	Title   string
+	From    string
+	Time    time.Time
+	// Severity is the name of the severity, e.g. "critical"
+	Severity string
+	Strategy string
+	// State is "firing" or "resolved", empty for plain messages
+	State string
+	// Incident identifies the alert within its strategy, e.g. to dedupe or resolve it
+	Incident string
+	Labels   map[string]string
+	Fields   []*notifier.Field
+	Values   map[string]float64
+	Price    *collector.WindowPrice
+}
+
+// newData returns the data of alert sent from from.
+func newData(alert *notifier.Alert, from string) *Data {
+	data := &Data{
+		Message:  alert.Text(),
+		Title:    alert.Title,
+		From:     from,
+		Time:     alert.Time,
+		Severity: alert.Severity.String(),
+		Strategy: alert.Strategy,
+		State:    alert.State,
+		Incident: alert.Incident,
+		Labels:   alert.Labels,
+		Fields:   alert.Fields,
+		Values:   alert.Values,
+		Price:    alert.Price,
+	}
+	if data.Time.IsZero() {
+		data.Time = time.Now()
+	}
+	return data
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+}
+
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(text)
+}
+
+func render(tmpl *template.Template, data *Data) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -1,7 +1,26 @@
 package wechat
 
+type Mention struct {
+	Users []string `json:"users"`
+	// Mobiles can only be mentioned by text messages, markdown messages reject them.
+	Mobiles []string `json:"mobiles"`
+}
+
 type Config struct {
 	Timeout    string `json:"timeout"`
 	WebhookURL string `json:"webhook_url"`
 	Throttle   string `json:"throttle"`
+
+	// MsgType is one of text, markdown, news or template_card, defaults to text.
+	MsgType string `json:"msg_type"`
+	// URL is the link opened from news and template_card messages.
+	URL    string `json:"url"`
+	PicURL string `json:"pic_url"`
+
+	// Mentions maps a severity name to the members to @ for notifications of that severity.
+	Mentions map[string]*Mention `json:"mentions"`
+
+	// MaxRetries is how many times a rate limited message is retried, 3 by default, 0 for none.
+	MaxRetries    *int   `json:"max_retries"`
+	RetryInterval string `json:"retry_interval"`
 }
@@ -0,0 +1,212 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	msgTypeText         = "text"
+	msgTypeMarkdown     = "markdown"
+	msgTypeNews         = "news"
+	msgTypeTemplateCard = "template_card"
+
+	// content size limits in bytes enforced by the webhook
+	maxTextBytes            = 2048
+	maxMarkdownBytes        = 4096
+	maxNewsTitleBytes       = 128
+	maxNewsDescriptionBytes = 512
+	// the sub title of a template card is limited in characters instead
+	maxCardSubTitleChars = 112
+)
+
+type TextContent struct {
+	Content             string   `json:"content"`
+	MentionedList       []string `json:"mentioned_list,omitempty"`
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+type MarkdownContent struct {
+	Content string `json:"content"`
+}
+
+type Article struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl,omitempty"`
+}
+
+type NewsContent struct {
+	Articles []*Article `json:"articles"`
+}
+
+type CardTitle struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+type CardKeyValue struct {
+	KeyName string `json:"keyname"`
+	Value   string `json:"value"`
+}
+
+type CardAction struct {
+	Type int    `json:"type"`
+	URL  string `json:"url"`
+}
+
+type TemplateCard struct {
+	CardType              string          `json:"card_type"`
+	MainTitle             *CardTitle      `json:"main_title"`
+	EmphasisContent       *CardTitle      `json:"emphasis_content,omitempty"`
+	SubTitleText          string          `json:"sub_title_text,omitempty"`
+	HorizontalContentList []*CardKeyValue `json:"horizontal_content_list,omitempty"`
+	CardAction            *CardAction     `json:"card_action"`
+}
+
+type NotificationMsg struct {
+	MsgType      string           `json:"msgtype"`
+	Text         *TextContent     `json:"text,omitempty"`
+	Markdown     *MarkdownContent `json:"markdown,omitempty"`
+	News         *NewsContent     `json:"news,omitempty"`
+	TemplateCard *TemplateCard    `json:"template_card,omitempty"`
+}
+
+func (n *NotificationMsg) ToJSON() []byte {
+	raw, _ := json.Marshal(n)
+	return raw
+}
+
+type Response struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// buildMessages renders an alert as one or more webhook messages, long text and
+// markdown contents are split into several messages to stay within the size limits.
+func (w *Notifier) buildMessages(alert *notifier.Alert) []*NotificationMsg {
+	mention := w.config.Mentions[alert.Severity.String()]
+	msg := alert.Text()
+
+	switch w.config.MsgType {
+	case msgTypeMarkdown:
+		content := msg
+		if !strings.HasPrefix(strings.TrimSpace(msg), alert.Title) {
+			content = fmt.Sprintf("### %s\n%s", alert.Title, msg)
+		}
+		if mention != nil {
+			mentions := make([]string, 0, len(mention.Users))
+			for _, user := range mention.Users {
+				mentions = append(mentions, fmt.Sprintf("<@%s>", user))
+			}
+			content += "\n" + strings.Join(mentions, " ")
+		}
+
+		var messages []*NotificationMsg
+		for _, chunk := range splitContent(content, maxMarkdownBytes) {
+			messages = append(messages, &NotificationMsg{
+				MsgType:  msgTypeMarkdown,
+				Markdown: &MarkdownContent{Content: chunk},
+			})
+		}
+		return messages
+	case msgTypeNews:
+		return []*NotificationMsg{{
+			MsgType: msgTypeNews,
+			News: &NewsContent{Articles: []*Article{{
+				Title:       truncateBytes(alert.Title, maxNewsTitleBytes),
+				Description: truncateBytes(msg, maxNewsDescriptionBytes),
+				URL:         w.config.URL,
+				PicURL:      w.config.PicURL,
+			}}},
+		}}
+	case msgTypeTemplateCard:
+		card := &TemplateCard{
+			CardType:     "text_notice",
+			MainTitle:    &CardTitle{Title: alert.Title},
+			SubTitleText: truncateChars(msg, maxCardSubTitleChars),
+			CardAction:   &CardAction{Type: 1, URL: w.config.URL},
+		}
+		if change, exist := alert.Values[notifier.ValueRelativeChange]; exist {
+			card.EmphasisContent = &CardTitle{
+				Title: fmt.Sprintf("%v%%", change),
+				Desc:  alert.Labels[notifier.LabelPair],
+			}
+		}
+		for _, field := range alert.Fields {
+			card.HorizontalContentList = append(card.HorizontalContentList, &CardKeyValue{KeyName: field.Name, Value: field.Value})
+		}
+		return []*NotificationMsg{{MsgType: msgTypeTemplateCard, TemplateCard: card}}
+	default:
+		var messages []*NotificationMsg
+		for i, chunk := range splitContent(msg, maxTextBytes) {
+			text := &TextContent{Content: chunk}
+			if mention != nil && i == 0 {
+				text.MentionedList = mention.Users
+				text.MentionedMobileList = mention.Mobiles
+			}
+			messages = append(messages, &NotificationMsg{MsgType: msgTypeText, Text: text})
+		}
+		return messages
+	}
+}
+
+// truncateBytes cuts content to at most limit bytes, on a character boundary.
+func truncateBytes(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut]
+}
+
+// truncateChars cuts content to at most limit characters.
+func truncateChars(content string, limit int) string {
+	runes := []rune(content)
+	if len(runes) <= limit {
+		return content
+	}
+	return string(runes[:limit])
+}
+
+// splitContent splits content into chunks of at most limit bytes, preferring
+// line boundaries and never splitting a UTF-8 character.
+func splitContent(content string, limit int) []string {
+	var chunks []string
+	current := &strings.Builder{}
+
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if current.Len()+len(line) <= limit {
+			current.WriteString(line)
+			continue
+		}
+		flush()
+
+		for len(line) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			chunks = append(chunks, line[:cut])
+			line = line[cut:]
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return chunks
+}
@@ -4,100 +4,154 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
 	"github.com/azraeljack/crypto-monitor/notifier"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
-	"sync"
+	"sort"
 	"time"
 )
 
-type TextContent struct {
-	Content string `json:"content"`
-}
-
-type NotificationMsg struct {
-	MsgType string      `json:"msgtype"`
-	Text    TextContent `json:"text"`
-}
-
-func (n *NotificationMsg) ToJSON() []byte {
-	raw, _ := json.Marshal(n)
-	return raw
-}
+// errCodeRateLimited is returned when the webhook exceeds 20 messages per minute.
+const errCodeRateLimited = 45009
 
 type Notifier struct {
-	webhookURL string
+	config     *Config
 	httpClient *http.Client
 
-	throttle time.Duration
+	throttler *notifier.Throttler
 
-	lastNotifiedTimes sync.Map
-	ctx               context.Context
+	maxRetries    int
+	retryInterval time.Duration
+
+	ctx context.Context
 }
 
-func NewWechatNotifier(ctx context.Context, rawConf json.RawMessage) notifier.Notifier {
+func NewWechatNotifier(ctx context.Context, rawConf json.RawMessage) (notifier.Notifier, error) {
 	conf := &Config{}
-	if err := json.Unmarshal(rawConf, conf); err != nil {
-		log.Panic("failed to parse wechat notifier config", err)
+	if err := config.DecodeNotifier(rawConf, conf); err != nil {
+		return nil, err
 	}
 
-	timeout, err := time.ParseDuration(conf.Timeout)
-	if err != nil {
-		timeout = 5 * time.Second
+	errs := config.Errors{}
+	timeout := config.ParseDuration(&errs, "timeout", conf.Timeout, 5*time.Second)
+	throttle := config.ParseDuration(&errs, "throttle", conf.Throttle, 5*time.Second)
+	retryInterval := config.ParseDuration(&errs, "retry_interval", conf.RetryInterval, 15*time.Second)
+
+	if len(conf.MsgType) == 0 {
+		conf.MsgType = msgTypeText
+	}
+	maxRetries := 3
+	if conf.MaxRetries != nil {
+		maxRetries = *conf.MaxRetries
+		if maxRetries < 0 {
+			errs.Addf("max_retries", "must not be negative")
+		}
 	}
 
-	throttle, err := time.ParseDuration(conf.Throttle)
-	if err != nil {
-		throttle = 5 * time.Second
+	config.Require(&errs, "webhook_url", conf.WebhookURL)
+	config.OneOf(&errs, "msg_type", conf.MsgType, msgTypeText, msgTypeMarkdown, msgTypeNews, msgTypeTemplateCard)
+	if conf.MsgType == msgTypeNews || conf.MsgType == msgTypeTemplateCard {
+		config.Require(&errs, "url", conf.URL)
+	}
+	notifier.CheckSeverityKeys(&errs, "mentions", conf.Mentions)
+	if conf.MsgType == msgTypeMarkdown {
+		// markdown messages can only mention members by user id
+		severities := make([]string, 0, len(conf.Mentions))
+		for severity := range conf.Mentions {
+			severities = append(severities, severity)
+		}
+		sort.Strings(severities)
+		for _, severity := range severities {
+			if mention := conf.Mentions[severity]; mention != nil && len(mention.Mobiles) > 0 {
+				errs.Addf(config.JoinPath(config.JoinPath("mentions", severity), "mobiles"), "not supported by markdown messages")
+			}
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
 	}
 
 	return &Notifier{
-		webhookURL: conf.WebhookURL,
-		throttle:   throttle,
+		config:        conf,
+		throttler:     notifier.NewThrottler(ctx, throttle),
+		maxRetries:    maxRetries,
+		retryInterval: retryInterval,
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
 		ctx: ctx,
-	}
+	}, nil
+}
+
+func (w *Notifier) Notify(msg, from string, throttle bool) error {
+	return w.NotifyAlert(notifier.AlertOf(msg, nil), from, throttle)
 }
 
-func (w *Notifier) Notify(msg, from string, throttle bool) {
+func (w *Notifier) NotifyAlert(alert *notifier.Alert, from string, throttle bool) error {
 	log.Info("sending wechat notification...")
-	log.Debugf("wechat payload: %v", msg)
-
-	if throttle {
-		currentTime := time.Now().UnixNano()
-		lastNotifiedTime, ok := w.lastNotifiedTimes.Load(from)
-		if ok && lastNotifiedTime.(int64)+int64(w.throttle) > currentTime {
-			log.Infof("wechat notifiation throttled, ignore message")
-			return
-		}
+	log.Debugf("wechat payload: %v", alert.Text())
 
-		w.lastNotifiedTimes.Store(from, currentTime)
+	if throttle && !w.throttler.Allow(from) {
+		return notifier.ErrThrottled
 	}
 
-	payload := &NotificationMsg{
-		MsgType: "text",
-		Text: TextContent{
-			Content: msg,
-		},
+	for _, payload := range w.buildMessages(alert) {
+		if err := w.sendWithRetry(payload); err != nil {
+			return err
+		}
+	}
+	log.Info("successfully notified via wechat")
+	return nil
+}
+
+func (w *Notifier) sendWithRetry(payload *NotificationMsg) error {
+	for attempt := 0; ; attempt++ {
+		result, err := w.send(payload)
+		if err != nil {
+			return err
+		}
+		if result.ErrCode == 0 {
+			return nil
+		}
+		if result.ErrCode != errCodeRateLimited || attempt >= w.maxRetries {
+			return fmt.Errorf("[%d] %s", result.ErrCode, result.ErrMsg)
+		}
+
+		log.Warnf("wechat webhook rate limited, retry in %v", w.retryInterval)
+		select {
+		case <-time.After(w.retryInterval):
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
 	}
-	request, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.webhookURL, bytes.NewReader(payload.ToJSON()))
+}
+
+func (w *Notifier) send(payload *NotificationMsg) (*Response, error) {
+	request, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.config.WebhookURL, bytes.NewReader(payload.ToJSON()))
 	if err != nil {
-		log.Errorf("notify fail: %v", err)
-		return
+		return nil, err
 	}
 	request.Header.Add("content-type", "application/json")
 
 	resp, err := w.httpClient.Do(request)
 	if err != nil {
-		log.Errorf("notify fail: %v", err)
-		return
+		return nil, err
 	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		errorMsg, _ := io.ReadAll(resp.Body)
-		log.Errorf("notify fail: %s", errorMsg)
+		return nil, fmt.Errorf("%s", body)
 	}
-	log.Info("successfully notified via wechat")
+
+	// the webhook reports failures with HTTP 200 and a non-zero errcode
+	result := &Response{}
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("unexpected response: %s", body)
+	}
+	return result, nil
 }
@@ -0,0 +1,91 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  string
+		requests int32
+	}{
+		{name: "default", requests: 4},
+		{name: "none", retries: `, "max_retries": 0`, requests: 1},
+		{name: "one", retries: `, "max_retries": 1`, requests: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				_, _ = fmt.Fprintf(w, `{"errcode": %d, "errmsg": "rate limited"}`, errCodeRateLimited)
+			}))
+			defer server.Close()
+
+			rawConf := fmt.Sprintf(`{"type": "wechat", "webhook_url": %q, "retry_interval": "1ms"%s}`, server.URL, test.retries)
+			n, err := NewWechatNotifier(context.Background(), []byte(rawConf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Notify("价格波动", "test", false); err == nil {
+				t.Error("rate limited notification succeeded")
+			}
+			if requests := atomic.LoadInt32(&requests); requests != test.requests {
+				t.Errorf("sent %d requests, want %d", requests, test.requests)
+			}
+		})
+	}
+
+	if _, err := NewWechatNotifier(context.Background(), []byte(`{"type": "wechat", "webhook_url": "http://localhost", "max_retries": -1}`)); err == nil {
+		t.Error("negative max_retries accepted")
+	}
+}
+
+func TestBuildMessagesTruncates(t *testing.T) {
+	alert := &notifier.Alert{Title: strings.Repeat("价格波动", 20), Body: strings.Repeat("价格波动", 100)}
+
+	news := &Notifier{config: &Config{MsgType: msgTypeNews, URL: "http://localhost"}}
+	article := news.buildMessages(alert)[0].News.Articles[0]
+	if len(article.Title) > maxNewsTitleBytes || !utf8.ValidString(article.Title) {
+		t.Errorf("news title of %d bytes, want at most %d valid ones", len(article.Title), maxNewsTitleBytes)
+	}
+	description := article.Description
+	if len(description) > maxNewsDescriptionBytes || !utf8.ValidString(description) {
+		t.Errorf("news description of %d bytes, want at most %d valid ones", len(description), maxNewsDescriptionBytes)
+	}
+
+	card := &Notifier{config: &Config{MsgType: msgTypeTemplateCard, URL: "http://localhost"}}
+	subTitle := card.buildMessages(alert)[0].TemplateCard.SubTitleText
+	if count := utf8.RuneCountInString(subTitle); count != maxCardSubTitleChars {
+		t.Errorf("card sub title of %d characters, want %d", count, maxCardSubTitleChars)
+	}
+}
+
+func TestMarkdownRejectsMobiles(t *testing.T) {
+	tests := []struct {
+		msgType string
+		valid   bool
+	}{
+		{msgType: msgTypeText, valid: true},
+		{msgType: msgTypeMarkdown},
+	}
+	for _, test := range tests {
+		rawConf := fmt.Sprintf(`{"type": "wechat", "webhook_url": "http://localhost", "msg_type": %q, "mentions": {"critical": {"users": ["alice"], "mobiles": ["13800000000"]}}}`, test.msgType)
+		_, err := NewWechatNotifier(context.Background(), []byte(rawConf))
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%s with mobiles: got error %v, want valid %v", test.msgType, err, test.valid)
+		}
+		if err != nil && !strings.Contains(err.Error(), "mentions.critical.mobiles") {
+			t.Errorf("%s with mobiles: error %q doesn't name the field", test.msgType, err)
+		}
+	}
+}
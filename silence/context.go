@@ -0,0 +1,16 @@
+package silence
+
+import "context"
+
+type silencerKey struct{}
+
+// NewContext returns a context carrying the silencer for the components built with it.
+func NewContext(ctx context.Context, s *Silencer) context.Context {
+	return context.WithValue(ctx, silencerKey{}, s)
+}
+
+// FromContext returns the silencer of ctx, or nil if it carries none.
+func FromContext(ctx context.Context) *Silencer {
+	s, _ := ctx.Value(silencerKey{}).(*Silencer)
+	return s
+}
@@ -0,0 +1,77 @@
+package silence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"time"
+	// quiet hours name their time zone, which the host may have no database of
+	_ "time/tzdata"
+)
+
+// QuietHours is a daily time range in a time zone, it wraps around midnight if it ends
+// before it starts.
+type QuietHours struct {
+	start    int
+	end      int
+	location *time.Location
+}
+
+func ParseQuietHours(conf *config.QuietHours) (*QuietHours, error) {
+	errs := config.Errors{}
+	hours := &QuietHours{
+		start:    parseClock(&errs, "start", conf.Start),
+		end:      parseClock(&errs, "end", conf.End),
+		location: time.Local,
+	}
+	if len(conf.Timezone) > 0 {
+		location, err := time.LoadLocation(conf.Timezone)
+		if err != nil {
+			errs.Addf("timezone", "unknown time zone %q", conf.Timezone)
+		} else {
+			hours.location = location
+		}
+	}
+	if len(errs) == 0 && hours.start == hours.end {
+		errs.Add("end", errors.New("must differ from start"))
+	}
+	return hours, errs.Err()
+}
+
+// parseClock parses a required "15:04" time of day into minutes since midnight.
+func parseClock(errs *config.Errors, field, value string) int {
+	config.Require(errs, field, value)
+	if len(value) == 0 {
+		return 0
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		errs.Addf(field, "invalid time of day %q, expecting e.g. 22:00", value)
+		return 0
+	}
+	return clock.Hour()*60 + clock.Minute()
+}
+
+// Active reports whether t is within the quiet hours.
+func (q *QuietHours) Active(t time.Time) bool {
+	t = t.In(q.location)
+	minute := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// End returns when the quiet hours next end after t.
+func (q *QuietHours) End(t time.Time) time.Time {
+	t = t.In(q.location)
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, q.location)
+	if !end.After(t) {
+		end = time.Date(t.Year(), t.Month(), t.Day()+1, q.end/60, q.end%60, 0, 0, q.location)
+	}
+	return end
+}
+
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", q.start/60, q.start%60, q.end/60, q.end%60, q.location)
+}
@@ -0,0 +1,183 @@
+package silence
+
+import (
+	"github.com/azraeljack/crypto-monitor/state"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Silence suppresses the alerts of a strategy on a pair until it expires, an empty
+// strategy or pair matches any.
+type Silence struct {
+	Strategy string    `json:"strategy,omitempty"`
+	Pair     string    `json:"pair,omitempty"`
+	Until    time.Time `json:"until"`
+}
+
+// key identifies the silence by what it matches, silencing the same again extends it.
+func (s *Silence) key() string {
+	values := url.Values{}
+	if len(s.Strategy) > 0 {
+		values.Set("strategy", s.Strategy)
+	}
+	if len(s.Pair) > 0 {
+		values.Set("pair", s.Pair)
+	}
+	if len(values) == 0 {
+		return "all"
+	}
+	return values.Encode()
+}
+
+func parseKey(key string, until time.Time) *Silence {
+	silence := &Silence{Until: until}
+	if key == "all" {
+		return silence
+	}
+	values, _ := url.ParseQuery(key)
+	silence.Strategy = values.Get("strategy")
+	silence.Pair = values.Get("pair")
+	return silence
+}
+
+func (s *Silence) Matches(strategy, pair string) bool {
+	return (len(s.Strategy) == 0 || s.Strategy == strategy) && (len(s.Pair) == 0 || s.Pair == pair)
+}
+
+// Silencer keeps the silences in the state store, so they survive restarts, and summarizes
+// the alerts each silence suppressed once it ends.
+type Silencer struct {
+	silences *state.Cache
+	// onEnd sends the summary of the alerts of a strategy suppressed by an ended silence
+	onEnd func(strategy string, summary *Summary)
+
+	mutex      sync.Mutex
+	stopped    bool
+	suppressed map[string]*suppressedAlerts
+}
+
+// suppressedAlerts are the alerts a silence suppressed, by strategy.
+type suppressedAlerts struct {
+	timer      *time.Timer
+	strategies map[string]*Summary
+}
+
+func NewSilencer(silences *state.Cache, onEnd func(strategy string, summary *Summary)) *Silencer {
+	return &Silencer{
+		silences:   silences,
+		onEnd:      onEnd,
+		suppressed: make(map[string]*suppressedAlerts),
+	}
+}
+
+// Add silences the matching alerts until the silence expires, extending or shortening the
+// silence matching the same if any.
+func (s *Silencer) Add(silence *Silence) {
+	s.silences.Set(silence.key(), time.Until(silence.Until))
+	log.Infof("silenced %s until %v", silence.key(), silence.Until.Format(time.RFC3339))
+}
+
+// Remove ends the silence matching strategy and pair, and reports whether there was one.
+func (s *Silencer) Remove(strategy, pair string) bool {
+	key := (&Silence{Strategy: strategy, Pair: pair}).key()
+	if !s.silences.Has(key) {
+		return false
+	}
+	s.silences.Delete(key)
+	log.Infof("silence %s removed", key)
+	s.end(key)
+	return true
+}
+
+// List returns the active silences sorted by expiry.
+func (s *Silencer) List() []*Silence {
+	var silences []*Silence
+	for key, until := range s.silences.Entries() {
+		silences = append(silences, parseKey(key, until))
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		if !silences[i].Until.Equal(silences[j].Until) {
+			return silences[i].Until.Before(silences[j].Until)
+		}
+		return silences[i].key() < silences[j].key()
+	})
+	return silences
+}
+
+// Match returns the silence suppressing an alert of strategy on pair, counting the alert in
+// its summary, or nil if none does.
+func (s *Silencer) Match(strategy, pair string) *Silence {
+	if s == nil {
+		return nil
+	}
+
+	var match *Silence
+	for _, silence := range s.List() {
+		if silence.Matches(strategy, pair) {
+			match = silence
+			break
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := match.key()
+	pending, exist := s.suppressed[key]
+	if !exist {
+		pending = &suppressedAlerts{strategies: make(map[string]*Summary)}
+		pending.timer = time.AfterFunc(time.Until(match.Until), func() { s.end(key) })
+		s.suppressed[key] = pending
+	}
+	summary, exist := pending.strategies[strategy]
+	if !exist {
+		summary = NewSummary()
+		pending.strategies[strategy] = summary
+	}
+	label := pair
+	if len(label) == 0 {
+		label = strategy
+	}
+	summary.Add(label)
+	return match
+}
+
+// end sends the summaries of a silence, unless it was extended in the meantime.
+func (s *Silencer) end(key string) {
+	s.mutex.Lock()
+	pending, exist := s.suppressed[key]
+	if !exist || s.stopped {
+		s.mutex.Unlock()
+		return
+	}
+	if until, active := s.silences.Entries()[key]; active {
+		pending.timer.Reset(time.Until(until))
+		s.mutex.Unlock()
+		return
+	}
+	pending.timer.Stop()
+	delete(s.suppressed, key)
+	s.mutex.Unlock()
+
+	for strategy, summary := range pending.strategies {
+		s.onEnd(strategy, summary)
+	}
+}
+
+// Stop drops the pending summaries, they are not sent anymore.
+func (s *Silencer) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	for key, pending := range s.suppressed {
+		pending.timer.Stop()
+		delete(s.suppressed, key)
+	}
+}
@@ -0,0 +1,46 @@
+package silence
+
+import (
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/templates"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Summary counts the alerts suppressed by a silence, by label such as their pair.
+type Summary struct {
+	mutex  sync.Mutex
+	counts map[string]int
+	total  int
+}
+
+func NewSummary() *Summary {
+	return &Summary{counts: make(map[string]int)}
+}
+
+func (s *Summary) Add(label string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.counts[label]++
+	s.total++
+}
+
+// Message lists the counts in the texts of a locale under a headline, e.g. "静默期间抑制了 3 条通知：".
+func (s *Summary) Message(texts *templates.Texts, headline string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	labels := make([]string, 0, len(s.counts))
+	for label := range s.counts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, texts.Summary, headline, s.total)
+	for _, label := range labels {
+		fmt.Fprintf(builder, texts.SummaryLine, label, s.counts[label])
+	}
+	return builder.String()
+}
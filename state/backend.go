@@ -0,0 +1,153 @@
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Backend persists the expiry of every key of the store. Save stores the expiries of put and
+// removes the deleted keys at once.
+type Backend interface {
+	Load() (map[string]time.Time, error)
+	Save(put map[string]time.Time, deleted []string) error
+	Close() error
+}
+
+var backends = map[string]func(path string) (Backend, error){
+	"file":  openFileBackend,
+	"bbolt": openBoltBackend,
+}
+
+// fileBackend keeps the keys in a JSON file, which is replaced atomically on every save.
+type fileBackend struct {
+	path     string
+	expiries map[string]time.Time
+}
+
+func openFileBackend(path string) (Backend, error) {
+	b := &fileBackend{path: path, expiries: make(map[string]time.Time)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b.expiries); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return b, nil
+}
+
+func (b *fileBackend) Load() (map[string]time.Time, error) {
+	expiries := make(map[string]time.Time, len(b.expiries))
+	for key, expiry := range b.expiries {
+		expiries[key] = expiry
+	}
+	return expiries, nil
+}
+
+func (b *fileBackend) Save(put map[string]time.Time, deleted []string) error {
+	for _, key := range deleted {
+		delete(b.expiries, key)
+	}
+	for key, expiry := range put {
+		b.expiries[key] = expiry
+	}
+	return b.write()
+}
+
+func (b *fileBackend) write() error {
+	data, err := json.Marshal(b.expiries)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), "."+filepath.Base(b.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
+
+func (b *fileBackend) Close() error {
+	return nil
+}
+
+var bucketName = []byte("expiries")
+
+// boltBackend keeps the keys in a bbolt database, with their expiry as big endian unix nanoseconds.
+type boltBackend struct {
+	db *bolt.DB
+}
+
+func openBoltBackend(path string) (Backend, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltBackend{db: db}, nil
+}
+
+func (b *boltBackend) Load() (map[string]time.Time, error) {
+	expiries := make(map[string]time.Time)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			if len(v) == 8 {
+				expiries[string(k)] = time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+			}
+			return nil
+		})
+	})
+	return expiries, err
+}
+
+func (b *boltBackend) Save(put map[string]time.Time, deleted []string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for _, key := range deleted {
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		for key, expiry := range put {
+			value := make([]byte, 8)
+			binary.BigEndian.PutUint64(value, uint64(expiry.UnixNano()))
+			if err := bucket.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
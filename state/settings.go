@@ -0,0 +1,26 @@
+package state
+
+import (
+	"github.com/azraeljack/crypto-monitor/config"
+)
+
+type Settings struct {
+	Backend string
+	Path    string
+}
+
+// ParseSettings validates the state config.
+func ParseSettings(conf *config.State) (*Settings, error) {
+	errs := config.Errors{}
+	config.Require(&errs, "path", conf.Path)
+
+	settings := &Settings{Backend: conf.Backend, Path: conf.Path}
+	if len(settings.Backend) == 0 {
+		settings.Backend = "file"
+	}
+	if _, exist := backends[settings.Backend]; !exist {
+		errs.Addf("backend", "unknown backend %q, expecting file or bbolt", settings.Backend)
+	}
+
+	return settings, errs.Err()
+}
@@ -0,0 +1,297 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pruneInterval is how often expired keys are removed from memory and the backend.
+	pruneInterval = time.Minute
+	// flushInterval is how often the keys changed since the last flush are persisted.
+	flushInterval = 5 * time.Second
+)
+
+// Store keeps keys until they expire, such as the alerts a strategy already sent in its
+// window. Keys live in memory and are persisted to the backend while the store is open,
+// so they survive restarts. Changes are persisted in the background every flushInterval
+// and when the store is closed, so setting a key never waits on the backend.
+type Store struct {
+	mutex    sync.Mutex
+	settings *Settings
+
+	expiries map[string]time.Time
+	prunedAt time.Time
+
+	// dirty are the keys changed since the last flush while open, with a zero expiry for
+	// deleted ones
+	dirty map[string]time.Time
+
+	// backendMutex serializes the flushes, the backend is only used while holding it
+	backendMutex sync.Mutex
+	backend      Backend
+	stopFlush    chan struct{}
+	flusher      sync.WaitGroup
+}
+
+// New returns a closed store, which keeps the keys in memory only until it's opened.
+func New() *Store {
+	return &Store{
+		expiries: make(map[string]time.Time),
+		dirty:    make(map[string]time.Time),
+	}
+}
+
+// Open loads the unexpired keys of the backend, and persists the keys set so far to it.
+func (s *Store) Open(settings *Settings) error {
+	s.backendMutex.Lock()
+	defer s.backendMutex.Unlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.backend != nil {
+		return errors.New("state store already open")
+	}
+
+	backend, err := backends[settings.Backend](settings.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open state store %s: %w", settings.Path, err)
+	}
+	persisted, err := backend.Load()
+	if err != nil {
+		backend.Close()
+		return fmt.Errorf("failed to load state store %s: %w", settings.Path, err)
+	}
+
+	now := time.Now()
+	put := make(map[string]time.Time)
+	var deleted []string
+	for key, expiry := range persisted {
+		if !expiry.After(now) {
+			deleted = append(deleted, key)
+		} else if expiry.After(s.expiries[key]) {
+			s.expiries[key] = expiry
+		}
+	}
+	for key, expiry := range s.expiries {
+		if !persisted[key].Equal(expiry) && expiry.After(now) {
+			put[key] = expiry
+		}
+	}
+	if err := backend.Save(put, deleted); err != nil {
+		log.Warnf("failed to persist state: %v", err)
+	}
+
+	s.backend = backend
+	s.settings = settings
+	s.prunedAt = now
+	s.dirty = make(map[string]time.Time)
+	s.stopFlush = make(chan struct{})
+	s.flusher.Add(1)
+	go s.runFlush(s.stopFlush)
+	log.Infof("persisting state in %s", settings.Path)
+	return nil
+}
+
+// Settings returns the settings the store was opened with, or nil if closed.
+func (s *Store) Settings() *Settings {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.settings
+}
+
+// Close persists the pending changes and closes the backend, the keys are kept in memory.
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	stop := s.stopFlush
+	s.stopFlush = nil
+	s.mutex.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	s.flusher.Wait()
+
+	s.backendMutex.Lock()
+	defer s.backendMutex.Unlock()
+
+	s.mutex.Lock()
+	s.settings = nil
+	dirty := s.takeDirty()
+	s.mutex.Unlock()
+	s.save(dirty)
+
+	err := s.backend.Close()
+	s.backend = nil
+	return err
+}
+
+func (s *Store) runFlush(stop chan struct{}) {
+	defer s.flusher.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// flush persists the keys changed since the last flush.
+func (s *Store) flush() {
+	s.backendMutex.Lock()
+	defer s.backendMutex.Unlock()
+
+	s.mutex.Lock()
+	dirty := s.takeDirty()
+	s.mutex.Unlock()
+	s.save(dirty)
+}
+
+func (s *Store) takeDirty() map[string]time.Time {
+	dirty := s.dirty
+	s.dirty = make(map[string]time.Time)
+	return dirty
+}
+
+// save persists changed keys, a zero expiry deletes the key, holding backendMutex.
+func (s *Store) save(dirty map[string]time.Time) {
+	if s.backend == nil || len(dirty) == 0 {
+		return
+	}
+	put := make(map[string]time.Time, len(dirty))
+	var deleted []string
+	for key, expiry := range dirty {
+		if expiry.IsZero() {
+			deleted = append(deleted, key)
+		} else {
+			put[key] = expiry
+		}
+	}
+	if err := s.backend.Save(put, deleted); err != nil {
+		log.Warnf("failed to persist state: %v", err)
+	}
+}
+
+// Cache returns the keys of a namespace, a nil store returns a namespace of its own store
+// kept in memory.
+func (s *Store) Cache(namespace string) *Cache {
+	if s == nil {
+		s = New()
+	}
+	return &Cache{store: s, prefix: namespace + "/"}
+}
+
+func (s *Store) has(key string, now time.Time) bool {
+	return s.expiries[key].After(now)
+}
+
+func (s *Store) set(key string, expiry, now time.Time) {
+	s.expiries[key] = expiry
+	s.markDirty(key, expiry)
+
+	if now.Sub(s.prunedAt) < pruneInterval {
+		return
+	}
+	s.prunedAt = now
+	for key, expiry := range s.expiries {
+		if !expiry.After(now) {
+			s.delete(key)
+		}
+	}
+}
+
+func (s *Store) delete(key string) {
+	delete(s.expiries, key)
+	s.markDirty(key, time.Time{})
+}
+
+// markDirty records a change to persist, a closed store persists all its keys once opened.
+func (s *Store) markDirty(key string, expiry time.Time) {
+	if s.settings != nil {
+		s.dirty[key] = expiry
+	}
+}
+
+// Cache is the namespace of a component in the store.
+type Cache struct {
+	store  *Store
+	prefix string
+}
+
+// Has reports whether key is set and not expired yet.
+func (c *Cache) Has(key string) bool {
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+	return c.store.has(c.prefix+key, time.Now())
+}
+
+// Set keeps key until ttl passes.
+func (c *Cache) Set(key string, ttl time.Duration) {
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+
+	now := time.Now()
+	c.store.set(c.prefix+key, now.Add(ttl), now)
+}
+
+// Acquire sets key for ttl unless it's already set, and reports whether it did.
+func (c *Cache) Acquire(key string, ttl time.Duration) bool {
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+
+	now := time.Now()
+	if c.store.has(c.prefix+key, now) {
+		return false
+	}
+	c.store.set(c.prefix+key, now.Add(ttl), now)
+	return true
+}
+
+// Entries returns the unexpired keys of the namespace along with their expiry.
+func (c *Cache) Entries() map[string]time.Time {
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+
+	now := time.Now()
+	entries := make(map[string]time.Time)
+	for key, expiry := range c.store.expiries {
+		if strings.HasPrefix(key, c.prefix) && expiry.After(now) {
+			entries[strings.TrimPrefix(key, c.prefix)] = expiry
+		}
+	}
+	return entries
+}
+
+// Delete forgets key.
+func (c *Cache) Delete(key string) {
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+
+	key = c.prefix + key
+	if _, exist := c.store.expiries[key]; exist {
+		c.store.delete(key)
+	}
+}
+
+type storeKey struct{}
+
+// NewContext returns a context carrying the store for the components built with it.
+func NewContext(ctx context.Context, s *Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, s)
+}
+
+// FromContext returns the store of ctx, or nil if it carries none.
+func FromContext(ctx context.Context) *Store {
+	s, _ := ctx.Value(storeKey{}).(*Store)
+	return s
+}
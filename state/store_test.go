@@ -0,0 +1,65 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorePersistsOnClose(t *testing.T) {
+	for backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			settings := &Settings{Backend: backend, Path: filepath.Join(t.TempDir(), "state")}
+
+			store := New()
+			store.Cache("throttle").Set("before", time.Hour)
+			if err := store.Open(settings); err != nil {
+				t.Fatal(err)
+			}
+			cache := store.Cache("throttle")
+			if !cache.Acquire("kept", time.Hour) {
+				t.Fatal("kept acquired twice")
+			}
+			cache.Set("deleted", time.Hour)
+			cache.Delete("deleted")
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened := New()
+			if err := reopened.Open(settings); err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			cache = reopened.Cache("throttle")
+			for key, want := range map[string]bool{"before": true, "kept": true, "deleted": false} {
+				if cache.Has(key) != want {
+					t.Errorf("key %s persisted: %v, want %v", key, !want, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreDefersWrites(t *testing.T) {
+	settings := &Settings{Backend: "file", Path: filepath.Join(t.TempDir(), "state")}
+	store := New()
+	if err := store.Open(settings); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	before, err := os.ReadFile(settings.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Cache("throttle").Set("key", time.Hour)
+	after, err := os.ReadFile(settings.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("state file written on set: %s", after)
+	}
+}
@@ -0,0 +1,122 @@
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracker records the activity of a component for the status endpoint. Components get
+// theirs from the context they are built with, the methods of a nil Tracker do nothing.
+type Tracker struct {
+	name string
+
+	mutex        sync.Mutex
+	lastActivity time.Time
+	lastError    error
+	lastErrorAt  time.Time
+	counts       map[string]uint64
+}
+
+// Snapshot is the state of a Tracker at one point in time.
+type Snapshot struct {
+	LastActivity *time.Time        `json:"last_activity,omitempty"`
+	LastError    string            `json:"last_error,omitempty"`
+	LastErrorAt  *time.Time        `json:"last_error_at,omitempty"`
+	Counts       map[string]uint64 `json:"counts"`
+}
+
+func NewTracker(name string) *Tracker {
+	return &Tracker{name: name, counts: make(map[string]uint64)}
+}
+
+// Name returns the name of the tracked component.
+func (t *Tracker) Name() string {
+	if t == nil {
+		return ""
+	}
+	return t.name
+}
+
+// Record counts an event and marks the component as active.
+func (t *Tracker) Record(event string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.counts[event]++
+	t.lastActivity = time.Now()
+}
+
+// Count counts an event without marking the component as active, e.g. dropped data.
+func (t *Tracker) Count(event string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.counts[event]++
+}
+
+// Error counts an error and keeps it as the last one.
+func (t *Tracker) Error(err error) {
+	if t == nil || err == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.counts["errors"]++
+	t.lastError = err
+	t.lastErrorAt = time.Now()
+}
+
+// LastActivity returns when an event was last recorded, zero if never.
+func (t *Tracker) LastActivity() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.lastActivity
+}
+
+func (t *Tracker) Snapshot() *Snapshot {
+	snapshot := &Snapshot{Counts: make(map[string]uint64)}
+	if t == nil {
+		return snapshot
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for event, count := range t.counts {
+		snapshot.Counts[event] = count
+	}
+	if !t.lastActivity.IsZero() {
+		lastActivity := t.lastActivity
+		snapshot.LastActivity = &lastActivity
+	}
+	if t.lastError != nil {
+		lastErrorAt := t.lastErrorAt
+		snapshot.LastError = t.lastError.Error()
+		snapshot.LastErrorAt = &lastErrorAt
+	}
+	return snapshot
+}
+
+type trackerKey struct{}
+
+// NewContext returns a context carrying t for the component built with it.
+func NewContext(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext returns the Tracker of ctx, or nil if it carries none.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
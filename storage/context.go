@@ -0,0 +1,16 @@
+package storage
+
+import "context"
+
+type storeKey struct{}
+
+// NewContext returns a context carrying the store for the strategies built with it.
+func NewContext(ctx context.Context, s *Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, s)
+}
+
+// FromContext returns the store of ctx, or nil if it carries none.
+func FromContext(ctx context.Context) *Store {
+	s, _ := ctx.Value(storeKey{}).(*Store)
+	return s
+}
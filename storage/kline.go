@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Kline is the price of a pair over a period. A raw price sample is a kline of a
+// single sample without resolution, downsampling merges them into coarser klines.
+type Kline struct {
+	OpenTime   time.Time     `json:"open_time"`
+	CloseTime  time.Time     `json:"close_time"`
+	Open       float64       `json:"open"`
+	High       float64       `json:"high"`
+	Low        float64       `json:"low"`
+	Close      float64       `json:"close"`
+	Samples    int           `json:"samples"`
+	Resolution time.Duration `json:"resolution"`
+}
+
+func (k *Kline) ToJSON() []byte {
+	data, _ := json.Marshal(k)
+	return data
+}
+
+func sample(t time.Time, price float64) *Kline {
+	return &Kline{OpenTime: t, CloseTime: t, Open: price, High: price, Low: price, Close: price, Samples: 1}
+}
+
+// merge combines klines sorted by time into one starting at openTime.
+func merge(openTime time.Time, resolution time.Duration, klines []*Kline) *Kline {
+	merged := &Kline{
+		OpenTime:   openTime,
+		CloseTime:  klines[len(klines)-1].CloseTime,
+		Open:       klines[0].Open,
+		High:       klines[0].High,
+		Low:        klines[0].Low,
+		Close:      klines[len(klines)-1].Close,
+		Resolution: resolution,
+	}
+	for _, k := range klines {
+		if k.High > merged.High {
+			merged.High = k.High
+		}
+		if k.Low < merged.Low {
+			merged.Low = k.Low
+		}
+		merged.Samples += k.Samples
+	}
+	return merged
+}
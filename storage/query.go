@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"time"
+)
+
+// PriceAt returns the last kline of pair closed at or before t, e.g. to compare with the
+// price 4h ago. An empty collector looks at every collector and returns the latest kline.
+func (s *Store) PriceAt(collector, pair string, t time.Time) (*Kline, error) {
+	db := s.getDB()
+	if db == nil {
+		return nil, ErrClosed
+	}
+
+	var found *Kline
+	err := db.View(func(tx *bolt.Tx) error {
+		return forEachPair(tx, collector, pair, func(bucket *bolt.Bucket) error {
+			cursor := bucket.Cursor()
+			key, value := cursor.Seek(timeKey(t))
+			if key == nil || !bytes.Equal(key, timeKey(t)) {
+				key, value = cursor.Prev()
+			}
+			// a downsampled kline opened before t may close after it
+			for ; key != nil; key, value = cursor.Prev() {
+				k := &Kline{}
+				if err := json.Unmarshal(value, k); err != nil {
+					return err
+				}
+				if k.CloseTime.After(t) {
+					continue
+				}
+				if found == nil || k.CloseTime.After(found.CloseTime) {
+					found = k
+				}
+				break
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// Range returns the klines of pair opened within [from, to) in time order, a zero from
+// starts at the first kline. An empty collector returns the klines of every collector.
+func (s *Store) Range(collector, pair string, from, to time.Time) ([]*Kline, error) {
+	db := s.getDB()
+	if db == nil {
+		return nil, ErrClosed
+	}
+
+	// the keys are nanoseconds since the epoch, the zero time doesn't fit in them
+	if epoch := time.Unix(0, 0); from.Before(epoch) {
+		from = epoch
+	}
+
+	var klines []*Kline
+	err := db.View(func(tx *bolt.Tx) error {
+		return forEachPair(tx, collector, pair, func(bucket *bolt.Bucket) error {
+			cursor := bucket.Cursor()
+			end := timeKey(to)
+			for key, value := cursor.Seek(timeKey(from)); key != nil && bytes.Compare(key, end) < 0; key, value = cursor.Next() {
+				k := &Kline{}
+				if err := json.Unmarshal(value, k); err != nil {
+					return err
+				}
+				klines = append(klines, k)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(klines, func(i, j int) bool {
+		return klines[i].OpenTime.Before(klines[j].OpenTime)
+	})
+	return klines, nil
+}
+
+func forEachPair(tx *bolt.Tx, collector, pair string, fn func(bucket *bolt.Bucket) error) error {
+	visit := func(collectorBucket *bolt.Bucket) error {
+		if collectorBucket == nil {
+			return nil
+		}
+		if pairBucket := collectorBucket.Bucket([]byte(pair)); pairBucket != nil {
+			return fn(pairBucket)
+		}
+		return nil
+	}
+
+	if len(collector) > 0 {
+		return visit(tx.Bucket([]byte(collector)))
+	}
+	return tx.ForEach(func(_ []byte, collectorBucket *bolt.Bucket) error {
+		return visit(collectorBucket)
+	})
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"github.com/azraeljack/crypto-monitor/config"
+	"sort"
+	"time"
+)
+
+type Settings struct {
+	Path            string
+	Retention       time.Duration
+	Downsample      []*Downsample
+	CompactInterval time.Duration
+}
+
+type Downsample struct {
+	After      time.Duration
+	Resolution time.Duration
+}
+
+// ParseSettings validates the storage config, downsampling rules are sorted by age.
+func ParseSettings(conf *config.Storage) (*Settings, error) {
+	errs := config.Errors{}
+	config.Require(&errs, "path", conf.Path)
+
+	settings := &Settings{
+		Path:            conf.Path,
+		Retention:       config.ParseDuration(&errs, "retention", conf.Retention, 0),
+		CompactInterval: config.ParseDuration(&errs, "compact_interval", conf.CompactInterval, 10*time.Minute),
+	}
+	if settings.CompactInterval == 0 {
+		errs.Addf("compact_interval", "must be positive")
+	}
+
+	for i, rule := range conf.Downsample {
+		path := config.Index("downsample", i)
+		downsample := &Downsample{
+			After:      config.ParseDuration(&errs, config.JoinPath(path, "after"), rule.After, 0),
+			Resolution: config.ParseDuration(&errs, config.JoinPath(path, "resolution"), rule.Resolution, 0),
+		}
+		if downsample.Resolution == 0 {
+			errs.Addf(config.JoinPath(path, "resolution"), "must be positive")
+		}
+		settings.Downsample = append(settings.Downsample, downsample)
+	}
+	sort.Slice(settings.Downsample, func(i, j int) bool {
+		return settings.Downsample[i].After < settings.Downsample[j].After
+	})
+
+	return settings, errs.Err()
+}
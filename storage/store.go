@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClosed   = errors.New("price storage is not open")
+	ErrNotFound = errors.New("no price recorded")
+)
+
+// Store records the prices fetched by collectors in a bbolt database, with a bucket per
+// collector holding a bucket per pair of klines keyed by their open time.
+type Store struct {
+	mutex    sync.RWMutex
+	db       *bolt.DB
+	settings *Settings
+
+	stop    context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// New returns a closed store, recording and querying fail with ErrClosed until it's opened.
+func New() *Store {
+	return &Store{}
+}
+
+// Open opens the database and compacts it every compact interval until closed.
+func (s *Store) Open(settings *Settings) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.db != nil {
+		return errors.New("price storage already open")
+	}
+
+	db, err := bolt.Open(settings.Path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open price storage %s: %w", settings.Path, err)
+	}
+	s.db = db
+	s.settings = settings
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	s.workers.Add(1)
+	go s.runCompaction(ctx)
+
+	log.Infof("recording prices in %s", settings.Path)
+	return nil
+}
+
+// Configure applies new retention and downsampling settings, the path only changes on reopen.
+func (s *Store) Configure(settings *Settings) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.settings != nil && s.settings.Path != settings.Path {
+		log.Warnf("price storage keeps using %s until restarted", s.settings.Path)
+	}
+	path := settings.Path
+	if s.settings != nil {
+		path = s.settings.Path
+	}
+	updated := *settings
+	updated.Path = path
+	s.settings = &updated
+}
+
+// Path returns the path of the open database, or an empty string if closed.
+func (s *Store) Path() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.db == nil {
+		return ""
+	}
+	return s.settings.Path
+}
+
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	db := s.db
+	s.db = nil
+	s.mutex.Unlock()
+
+	if db == nil {
+		return nil
+	}
+	s.stop()
+	s.workers.Wait()
+	return db.Close()
+}
+
+// Record stores a price sample of collector, it does nothing if the store is closed or nil.
+func (s *Store) Record(collector, pair string, t time.Time, price float64) error {
+	return s.RecordKline(collector, pair, sample(t, price))
+}
+
+// RecordKline stores a kline of collector, e.g. the open, high, low and close of a window
+// price, it does nothing if the store is closed or nil.
+func (s *Store) RecordKline(collector, pair string, k *Kline) error {
+	db := s.getDB()
+	if db == nil {
+		return nil
+	}
+
+	return db.Batch(func(tx *bolt.Tx) error {
+		collectorBucket, err := tx.CreateBucketIfNotExists([]byte(collector))
+		if err != nil {
+			return err
+		}
+		pairBucket, err := collectorBucket.CreateBucketIfNotExists([]byte(pair))
+		if err != nil {
+			return err
+		}
+		return pairBucket.Put(timeKey(k.OpenTime), k.ToJSON())
+	})
+}
+
+func (s *Store) getDB() *bolt.DB {
+	if s == nil {
+		return nil
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db
+}
+
+func (s *Store) runCompaction(ctx context.Context) {
+	defer s.workers.Done()
+
+	for {
+		s.mutex.RLock()
+		settings := s.settings
+		s.mutex.RUnlock()
+
+		start := time.Now()
+		if err := s.compact(settings, start); err != nil {
+			log.Errorf("failed to compact price storage: %v", err)
+		} else {
+			log.Debugf("compacted price storage in %v", time.Since(start))
+		}
+
+		select {
+		case <-time.After(settings.CompactInterval):
+		case <-ctx.Done():
+			log.Info("price storage compaction exited")
+			return
+		}
+	}
+}
+
+// compact drops the klines past retention and downsamples the older ones.
+func (s *Store) compact(settings *Settings, now time.Time) error {
+	db := s.getDB()
+	if db == nil {
+		return ErrClosed
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, collectorBucket *bolt.Bucket) error {
+			return collectorBucket.ForEach(func(pair, value []byte) error {
+				pairBucket := collectorBucket.Bucket(pair)
+				if value != nil || pairBucket == nil {
+					return nil
+				}
+
+				if settings.Retention > 0 {
+					if err := expire(pairBucket, now.Add(-settings.Retention)); err != nil {
+						return err
+					}
+				}
+				for _, rule := range settings.Downsample {
+					if err := downsample(pairBucket, now.Add(-rule.After), rule.Resolution); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	})
+}
+
+func expire(bucket *bolt.Bucket, before time.Time) error {
+	var expired [][]byte
+	cursor := bucket.Cursor()
+	for key, _ := cursor.First(); key != nil && keyTime(key).Before(before); key, _ = cursor.Next() {
+		expired = append(expired, key)
+	}
+	for _, key := range expired {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downsample merges the klines finer than resolution into klines of resolution, only
+// periods entirely before cutoff are merged.
+func downsample(bucket *bolt.Bucket, cutoff time.Time, resolution time.Duration) error {
+	type group struct {
+		openTime time.Time
+		keys     [][]byte
+		klines   []*Kline
+		finer    bool
+	}
+
+	var groups []*group
+	cursor := bucket.Cursor()
+	for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+		k := &Kline{}
+		if err := json.Unmarshal(value, k); err != nil {
+			return fmt.Errorf("corrupted kline at %v: %w", keyTime(key), err)
+		}
+
+		openTime := k.OpenTime.Truncate(resolution)
+		if openTime.Add(resolution).After(cutoff) {
+			break
+		}
+		if k.Resolution > resolution {
+			continue
+		}
+
+		if len(groups) == 0 || !groups[len(groups)-1].openTime.Equal(openTime) {
+			groups = append(groups, &group{openTime: openTime})
+		}
+		g := groups[len(groups)-1]
+		g.keys = append(g.keys, append([]byte(nil), key...))
+		g.klines = append(g.klines, k)
+		g.finer = g.finer || k.Resolution < resolution
+	}
+
+	for _, g := range groups {
+		if !g.finer {
+			continue
+		}
+		for _, key := range g.keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		sort.Slice(g.klines, func(i, j int) bool {
+			return g.klines[i].OpenTime.Before(g.klines[j].OpenTime)
+		})
+		if err := bucket.Put(timeKey(g.openTime), merge(g.openTime, resolution, g.klines).ToJSON()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key)))
+}
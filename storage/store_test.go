@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	s := New()
+	if err := s.Open(&Settings{Path: filepath.Join(t.TempDir(), "prices.db"), CompactInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func record(t *testing.T, s *Store, collector string, offset time.Duration, price float64) {
+	t.Helper()
+	if err := s.Record(collector, "BTC-USDT", t0.Add(offset), price); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recordKline(t *testing.T, s *Store, collector string, k *Kline) {
+	t.Helper()
+	if err := s.RecordKline(collector, "BTC-USDT", k); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func allKlines(t *testing.T, s *Store) []*Kline {
+	t.Helper()
+	klines, err := s.Range("", "BTC-USDT", time.Time{}, t0.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return klines
+}
+
+func TestCompactExpires(t *testing.T) {
+	s := openStore(t)
+	record(t, s, "binance", 0, 10)
+	record(t, s, "binance", 10*time.Minute, 11)
+	record(t, s, "binance", 20*time.Minute, 12)
+
+	if err := s.compact(&Settings{Retention: 15 * time.Minute}, t0.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	klines := allKlines(t, s)
+	if len(klines) != 1 || !klines[0].OpenTime.Equal(t0.Add(20*time.Minute)) {
+		t.Errorf("kept %d klines, want only the one within retention", len(klines))
+	}
+}
+
+func TestCompactDownsamples(t *testing.T) {
+	s := openStore(t)
+	for i, price := range []float64{10, 15, 5, 12} {
+		record(t, s, "binance", time.Duration(i)*time.Minute, price)
+	}
+	// coarser than the resolution, kept as is
+	recordKline(t, s, "binance", &Kline{
+		OpenTime: t0.Add(4 * time.Minute), CloseTime: t0.Add(64 * time.Minute),
+		Open: 1, High: 1, Low: 1, Close: 1, Samples: 1, Resolution: time.Hour,
+	})
+	// the period of these isn't entirely before the cutoff
+	record(t, s, "binance", 5*time.Minute, 20)
+	record(t, s, "binance", 9*time.Minute, 30)
+
+	settings := &Settings{Downsample: []*Downsample{{After: 2 * time.Minute, Resolution: 5 * time.Minute}}}
+	for i := 0; i < 2; i++ {
+		if err := s.compact(settings, t0.Add(10*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	klines := allKlines(t, s)
+	if len(klines) != 4 {
+		t.Fatalf("got %d klines, want the merged one, the coarser one and 2 samples", len(klines))
+	}
+	want := Kline{
+		OpenTime: t0, CloseTime: t0.Add(3 * time.Minute),
+		Open: 10, High: 15, Low: 5, Close: 12, Samples: 4, Resolution: 5 * time.Minute,
+	}
+	if got := *klines[0]; !got.OpenTime.Equal(want.OpenTime) || !got.CloseTime.Equal(want.CloseTime) ||
+		got.Open != want.Open || got.High != want.High || got.Low != want.Low || got.Close != want.Close ||
+		got.Samples != want.Samples || got.Resolution != want.Resolution {
+		t.Errorf("merged %+v, want %+v", got, want)
+	}
+	if klines[1].Resolution != time.Hour {
+		t.Errorf("second kline has resolution %v, want the coarser kline untouched", klines[1].Resolution)
+	}
+	for _, k := range klines[2:] {
+		if k.Samples != 1 || k.Resolution != 0 {
+			t.Errorf("kline at %v was downsampled, its period isn't before the cutoff", k.OpenTime)
+		}
+	}
+}
+
+func TestPriceAt(t *testing.T) {
+	s := openStore(t)
+	record(t, s, "binance", 0, 10)
+	record(t, s, "binance", time.Minute, 11)
+	// opened before 3m but closed after it
+	recordKline(t, s, "binance", &Kline{
+		OpenTime: t0.Add(2 * time.Minute), CloseTime: t0.Add(6 * time.Minute),
+		Open: 11, High: 14, Low: 9, Close: 13, Samples: 5, Resolution: 5 * time.Minute,
+	})
+	record(t, s, "binance", 10*time.Minute, 20)
+	record(t, s, "okx", 8*time.Minute, 30)
+
+	tests := []struct {
+		name      string
+		collector string
+		at        time.Duration
+		price     float64
+		err       error
+	}{
+		{name: "before the first kline", collector: "binance", at: -time.Second, err: ErrNotFound},
+		{name: "at the first key", collector: "binance", at: 0, price: 10},
+		{name: "between keys", collector: "binance", at: 30 * time.Second, price: 10},
+		{name: "at a key", collector: "binance", at: time.Minute, price: 11},
+		{name: "kline closing after", collector: "binance", at: 3 * time.Minute, price: 11},
+		{name: "at the close of a kline", collector: "binance", at: 6 * time.Minute, price: 13},
+		{name: "at the last key", collector: "binance", at: 10 * time.Minute, price: 20},
+		{name: "past the last key", collector: "binance", at: time.Hour, price: 20},
+		{name: "latest of every collector", at: 9 * time.Minute, price: 30},
+		{name: "unknown collector", collector: "kraken", at: time.Hour, err: ErrNotFound},
+	}
+	for _, test := range tests {
+		k, err := s.PriceAt(test.collector, "BTC-USDT", t0.Add(test.at))
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if k.Close != test.price {
+			t.Errorf("%s: got %v, want %v", test.name, k.Close, test.price)
+		}
+	}
+}
+
+func TestClosedStore(t *testing.T) {
+	s := New()
+	if err := s.Record("binance", "BTC-USDT", t0, 10); err != nil {
+		t.Errorf("recording in a closed store failed: %v", err)
+	}
+	if _, err := s.PriceAt("binance", "BTC-USDT", t0); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v, want %v", err, ErrClosed)
+	}
+}
@@ -6,4 +6,21 @@ type Config struct {
 	WindowSize string  `json:"window_size"`
 	Absolute   float64 `json:"absolute"`
 	Percentage float64 `json:"percentage"`
+
+	// CriticalAbsolute and CriticalPercentage mark changes reaching them critical, twice the
+	// thresholds by default if neither is set, a negative one is disabled.
+	CriticalAbsolute   float64 `json:"critical_absolute"`
+	CriticalPercentage float64 `json:"critical_percentage"`
+
+	// For is how long the change must stay over the thresholds before alerting, right away if empty.
+	For string `json:"for"`
+	// RepeatInterval is how often an ongoing alert is sent again, the window size by default.
+	RepeatInterval string `json:"repeat_interval"`
+	// SendResolved notifies once the change falls back under the thresholds, true by default.
+	SendResolved *bool `json:"send_resolved"`
+	// Cooldown is the least time between two alerts of the pair in the same direction.
+	Cooldown string `json:"cooldown"`
+	// Step alerts again as soon as the move extends by step more percentage points,
+	// e.g. every additional 2%, despite the repeat interval and cooldown.
+	Step float64 `json:"step"`
 }
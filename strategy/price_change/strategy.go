@@ -1,51 +1,34 @@
 package price_change
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/config"
+	"github.com/azraeljack/crypto-monitor/incident"
+	"github.com/azraeljack/crypto-monitor/lifecycle"
+	"github.com/azraeljack/crypto-monitor/metrics"
 	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/state"
+	"github.com/azraeljack/crypto-monitor/status"
 	"github.com/azraeljack/crypto-monitor/strategy"
-	cache "github.com/go-pkgz/expirable-cache/v2"
+	"github.com/azraeljack/crypto-monitor/templates"
 	log "github.com/sirupsen/logrus"
-	"html/template"
 	"math"
+	"sync"
 	"time"
 )
 
-var notificationTemplate = `发现价格波动：
-- 时间：{{.Time}}
-- 交易对：{{.Symbol1}} - {{.Symbol2}}
-- 当前价格：{{.CurrentPrice}}
-- 波动幅度：{{.Absolute}} ({{.Percentage}}%)
-- 成交笔数：{{.OrderNumber}}
-`
-
-type Notification struct {
-	Time         string
-	Symbol1      string
-	Symbol2      string
-	Absolute     string
-	Percentage   string
-	OrderNumber  string
-	CurrentPrice string
-}
-
-func NewNotification(symbol1, symbol2 string, price *collector.WindowPrice) *Notification {
-	return &Notification{
-		Time:         time.Now().Format("2006-01-02 15:04:05"),
-		Symbol1:      symbol1,
-		Symbol2:      symbol2,
-		CurrentPrice: fmt.Sprintf("%v", price.ClosePrice),
-		Absolute:     fmt.Sprintf("%v", price.AbsolutePriceChange),
-		Percentage:   fmt.Sprintf("%v", price.RelativePriceChange),
-		OrderNumber:  fmt.Sprintf("%v", price.OrderCount),
-	}
-}
+// rises and falls are separate incidents, named after their direction
+const (
+	directionUp   = "up"
+	directionDown = "down"
+)
 
 type Strategy struct {
+	name       string
 	windowSize time.Duration
 
 	symbol1 string
@@ -54,11 +37,20 @@ type Strategy struct {
 	absolute   float64
 	percentage float64
 
-	ctx        context.Context
-	priceCache cache.Cache[string, struct{}]
+	criticalAbsolute   float64
+	criticalPercentage float64
+
+	templates *templates.Set
+
+	ctx       context.Context
+	tracker   *status.Tracker
+	incidents *incident.Manager
 
 	collectors []collector.Collector
 	notifiers  []notifier.Notifier
+
+	// workers tracks the notifier worker and the notifications it sends
+	workers sync.WaitGroup
 }
 
 func (s *Strategy) AddCollectors(collector ...collector.Collector) {
@@ -71,95 +63,244 @@ func (s *Strategy) AddNotifiers(notifier ...notifier.Notifier) {
 
 func (s *Strategy) Run() {
 	log.Infof("start running price change strategy for [%s - %s]", s.symbol1, s.symbol2)
-	notifyPriceCh := make(chan *collector.WindowPrice, len(s.collectors)*20+1)
+	notifyPriceCh := make(chan *match, len(s.collectors)*20+1)
 
+	// listeners exit once their collector closes the price channel after the context is done
+	listeners := &sync.WaitGroup{}
 	for _, c := range s.collectors {
+		listeners.Add(1)
 		go func(col collector.Collector) {
-			newPrice := col.CollectWindowPrice(s.symbol1, s.symbol2, s.windowSize)
-			for {
-				select {
-				case price, ok := <-newPrice:
-					if !ok {
-						log.Info("price change strategy collector listener exit")
-						return
+			defer listeners.Done()
+
+			for price := range col.CollectWindowPrice(s.ctx, s.symbol1, s.symbol2, s.windowSize) {
+				s.tracker.Record("evaluations")
+				metrics.Evaluated(s.name, price.SymbolPair())
+				matched := s.matches(price)
+				if matched {
+					s.tracker.Count("matches")
+					metrics.Matched(s.name, price.SymbolPair(), s.severityOf(price).String())
+				} else {
+					log.Debugf("received unmatched price change, absolute: %v, relative: %v", price.AbsolutePriceChange, price.RelativePriceChange)
+				}
+
+				now := time.Now()
+				for _, direction := range []string{directionUp, directionDown} {
+					holds := matched && price.Rising() == (direction == directionUp)
+					current, notify := s.incidents.Update(price.Exchange, price.SymbolPair(), direction, holds, math.Abs(price.RelativePriceChange), now)
+					if current == nil {
+						continue
 					}
-					priceKey := fmt.Sprintf("%v", price.AbsolutePriceChange)
-					if _, exist := s.priceCache.Peek(priceKey); exist {
-						log.Infof("price already notified in this window")
+					if !notify {
+						log.Debugf("price %s incident of [%s - %s] is %s, not notifying", direction, s.symbol1, s.symbol2, current.State)
 						continue
 					}
-					if math.Abs(price.AbsolutePriceChange) >= s.absolute || math.Abs(price.RelativePriceChange) >= s.percentage {
-						s.priceCache.Set(priceKey, struct{}{}, s.windowSize)
-
-						select {
-						case notifyPriceCh <- price:
-							log.Infof("received strategy matched price change [%s - %s]: %s", s.symbol1, s.symbol2, price.String())
-						default:
-							log.Warnf("price change notify channel full, discard data: %s", price.String())
-						}
-					} else {
-						log.Debugf("received unmatched price change, absolute: %v, relative: %v", price.AbsolutePriceChange, price.RelativePriceChange)
+
+					select {
+					case notifyPriceCh <- &match{price: price, incident: current}:
+						log.Infof("received %s price change %s [%s - %s]: %s", current.State, direction, s.symbol1, s.symbol2, price.String())
+					default:
+						log.Warnf("price change notify channel full, discard data: %s", price.String())
+						s.tracker.Count("dropped")
+						metrics.MatchDropped(s.name, price.SymbolPair())
 					}
-				case <-s.ctx.Done():
-					log.Info("price change strategy collector listener exit")
-					return
 				}
 			}
+			log.Info("price change strategy collector listener exit")
 		}(c)
 	}
 
+	s.workers.Add(1)
 	go func() {
+		defer s.workers.Done()
+
 		for {
 			select {
-			case price := <-notifyPriceCh:
-				for _, n := range s.notifiers {
-					go func(price *collector.WindowPrice, not notifier.Notifier) {
-						log.Info("sending price change notification...")
-						log.Debugf("price change: %v", price.String())
-						tmpl := template.New("PriceChangeNotification")
-						if _, err := tmpl.Parse(notificationTemplate); err != nil {
-							log.Warnf("unable to parse template: %v", err)
-							return
-						}
-
-						stringWriter := bytes.NewBufferString("")
-						if err := tmpl.Execute(stringWriter, NewNotification(s.symbol1, s.symbol2, price)); err != nil {
-							log.Warnf("unable to redner template: %v", err)
-							return
-						}
-
-						not.Notify(stringWriter.String(), "PriceChange^"+price.SymbolPair(), true)
-						log.Infof("price change notifcation sent")
-					}(price, n)
-				}
+			case m := <-notifyPriceCh:
+				s.notify(m)
 			case <-s.ctx.Done():
-				log.Infof("price change notifer worker exit")
-				return
+				// deliver the prices matched before stopping
+				listeners.Wait()
+				for {
+					select {
+					case m := <-notifyPriceCh:
+						s.notify(m)
+					default:
+						log.Infof("price change notifer worker exit")
+						return
+					}
+				}
 			}
 		}
 	}()
 }
 
-func NewPriceChangeStrategy(ctx context.Context, rawConf json.RawMessage) strategy.Strategy {
+// Wait blocks until the listeners and pending notifications are done after the strategy's context is.
+func (s *Strategy) Wait(ctx context.Context) error {
+	return lifecycle.Wait(ctx, &s.workers)
+}
+
+// match is a price to notify along with the state of its incident.
+type match struct {
+	price    *collector.WindowPrice
+	incident *incident.Incident
+}
+
+func (s *Strategy) notify(m *match) {
+	price := m.price
+	from := "PriceChange^" + m.incident.Key()
+	data := templates.NewData(price)
+	data.Strategy, data.State = s.name, m.incident.State.String()
+	data.Window = s.windowSize.String()
+	data.AbsoluteThreshold, data.PercentageThreshold = s.absolute, s.percentage
+	details := &notifier.Details{
+		Strategy: s.name,
+		Severity: s.severityOf(price),
+		State:    m.incident.State.String(),
+		Incident: m.incident.Key(),
+		Price:    price,
+		Data:     data,
+	}
+	resolved := m.incident.State == incident.StateResolved
+	if resolved {
+		// resolutions are sent even if the alert was just throttled
+		from = "PriceChangeResolved^" + m.incident.Key()
+		details.Severity = notifier.SeverityInfo
+		data.Duration = m.incident.ResolvedAt.Sub(m.incident.ActiveAt).Truncate(time.Second).String()
+	}
+	data.Severity = details.Severity.String()
+
+	text, err := s.templates.Render(data, resolved)
+	if err != nil {
+		log.Warnf("unable to render price change notification: %v", err)
+		s.tracker.Error(err)
+		return
+	}
+	alert := s.alertOf(text, details)
+
+	for _, n := range s.notifiers {
+		s.workers.Add(1)
+		go func(not notifier.Notifier) {
+			defer s.workers.Done()
+
+			log.Info("sending price change notification...")
+			log.Debugf("price change: %v", price.String())
+			err := notifier.SendAlert(not, alert, from, true, details)
+			if err != nil && !errors.Is(err, notifier.ErrThrottled) && !errors.Is(err, notifier.ErrSilenced) {
+				log.Warnf("price change notification fail: %v", err)
+				s.tracker.Error(err)
+				return
+			}
+			log.Infof("price change notifcation sent")
+		}(n)
+	}
+}
+
+// alertOf structures the rendered notification text of a price change.
+func (s *Strategy) alertOf(text string, details *notifier.Details) *notifier.Alert {
+	price, data := details.Price, details.Data
+	priceName, changeName, tradesName := s.templates.FieldNames()
+	return &notifier.Alert{
+		Title:    details.Headline(),
+		Body:     text,
+		Severity: details.Severity,
+		State:    details.State,
+		Strategy: s.name,
+		Incident: details.Incident,
+		Time:     time.Now(),
+		Labels: map[string]string{
+			notifier.LabelPair:      price.SymbolPair(),
+			notifier.LabelExchange:  price.Exchange,
+			notifier.LabelDirection: data.Direction,
+		},
+		Fields: []*notifier.Field{
+			{Name: priceName, Value: data.Price},
+			{Name: changeName, Value: fmt.Sprintf("%s %s (%s%%)", data.Arrow, data.Absolute, data.Percentage)},
+			{Name: tradesName, Value: data.OrderNumber},
+		},
+		Values: map[string]float64{
+			notifier.ValuePrice:          price.ClosePrice,
+			notifier.ValueAbsoluteChange: price.AbsolutePriceChange,
+			notifier.ValueRelativeChange: price.RelativePriceChange,
+		},
+		Price: price,
+	}
+}
+
+// matches reports whether the price change reaches one of the thresholds, a zero threshold is disabled.
+func (s *Strategy) matches(price *collector.WindowPrice) bool {
+	return (s.absolute > 0 && math.Abs(price.AbsolutePriceChange) >= s.absolute) ||
+		(s.percentage > 0 && math.Abs(price.RelativePriceChange) >= s.percentage)
+}
+
+func (s *Strategy) severityOf(price *collector.WindowPrice) notifier.Severity {
+	if (s.criticalAbsolute > 0 && math.Abs(price.AbsolutePriceChange) >= s.criticalAbsolute) ||
+		(s.criticalPercentage > 0 && math.Abs(price.RelativePriceChange) >= s.criticalPercentage) {
+		return notifier.SeverityCritical
+	}
+	return notifier.SeverityWarning
+}
+
+func NewPriceChangeStrategy(ctx context.Context, rawConf json.RawMessage) (strategy.Strategy, error) {
+	component := &config.Component{}
+	if err := json.Unmarshal(rawConf, component); err != nil {
+		return nil, config.Errors{{Err: err}}
+	}
+
 	conf := &Config{}
-	if err := json.Unmarshal(rawConf, conf); err != nil {
-		log.Panic("failed to parse price change strategy config", err)
+	if err := config.DecodeStrategy(rawConf, conf); err != nil {
+		return nil, err
 	}
 
-	windowSize, err := time.ParseDuration(conf.WindowSize)
+	errs := config.Errors{}
+	config.Require(&errs, "symbol1", conf.Symbol1)
+	config.Require(&errs, "symbol2", conf.Symbol2)
+	windowSize := config.ParseDuration(&errs, "window_size", conf.WindowSize, 15*time.Minute)
+	if windowSize == 0 {
+		errs.Addf("window_size", "must be positive")
+	}
+	if conf.Absolute <= 0 && conf.Percentage <= 0 {
+		errs.Addf("percentage", "either absolute or percentage must be a positive threshold")
+	}
+	incidentLifecycle := &incident.Lifecycle{
+		For:            config.ParseDuration(&errs, "for", conf.For, 0),
+		RepeatInterval: config.ParseDuration(&errs, "repeat_interval", conf.RepeatInterval, windowSize),
+		SendResolved:   conf.SendResolved == nil || *conf.SendResolved,
+		Cooldown:       config.ParseDuration(&errs, "cooldown", conf.Cooldown, 0),
+		Step:           conf.Step,
+	}
+	if incidentLifecycle.RepeatInterval == 0 {
+		errs.Addf("repeat_interval", "must be positive")
+	}
+	if conf.Step < 0 {
+		errs.Addf("step", "must not be negative")
+	}
+	tmpls, err := templates.Parse(component.MessageTemplate, templates.LocaleFromContext(ctx))
 	if err != nil {
-		windowSize = 15 * time.Minute
+		errs.Add("message_template", err)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	if conf.CriticalAbsolute == 0 && conf.CriticalPercentage == 0 {
+		conf.CriticalAbsolute, conf.CriticalPercentage = 2*conf.Absolute, 2*conf.Percentage
 	}
 
 	return &Strategy{
+		name:       component.Name,
 		windowSize: windowSize,
 		symbol1:    conf.Symbol1,
 		symbol2:    conf.Symbol2,
 		absolute:   conf.Absolute,
 		percentage: conf.Percentage,
+
+		criticalAbsolute:   conf.CriticalAbsolute,
+		criticalPercentage: conf.CriticalPercentage,
+
+		templates:  tmpls,
 		ctx:        ctx,
-		priceCache: cache.NewCache[string, struct{}]().WithTTL(windowSize),
+		tracker:    status.FromContext(ctx),
+		incidents:  incident.NewManager(component.Name, incidentLifecycle, state.FromContext(ctx).Cache("incidents/"+component.Name)),
 		collectors: make([]collector.Collector, 0),
 		notifiers:  make([]notifier.Notifier, 0),
-	}
+	}, nil
 }
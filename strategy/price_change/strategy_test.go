@@ -0,0 +1,121 @@
+package price_change
+
+import (
+	"context"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/notifier"
+	"github.com/azraeljack/crypto-monitor/status"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCollector sends its prices one after the other and closes once ctx is done.
+type fakeCollector struct {
+	prices []*collector.WindowPrice
+}
+
+func (c *fakeCollector) CollectAvgPrice(ctx context.Context, symbol1, symbol2 string) <-chan float64 {
+	ch := make(chan float64)
+	close(ch)
+	return ch
+}
+
+func (c *fakeCollector) CollectWindowPrice(ctx context.Context, symbol1, symbol2 string, window time.Duration) <-chan *collector.WindowPrice {
+	ch := make(chan *collector.WindowPrice)
+	go func() {
+		defer close(ch)
+		for _, price := range c.prices {
+			select {
+			case ch <- price:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return ch
+}
+
+func (c *fakeCollector) Type() string {
+	return "fake"
+}
+
+func (c *fakeCollector) TestConnection() bool {
+	return true
+}
+
+// alertRecorder records the state, incident and relative change of the alerts it receives.
+type alertRecorder struct {
+	mutex  sync.Mutex
+	alerts []string
+}
+
+func (r *alertRecorder) Notify(msg, from string, throttle bool) error {
+	return r.NotifyAlert(notifier.AlertOf(msg, nil), from, throttle)
+}
+
+func (r *alertRecorder) NotifyAlert(alert *notifier.Alert, from string, throttle bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.alerts = append(r.alerts, fmt.Sprintf("%s %s %v", alert.State, alert.Incident, alert.Values[notifier.ValueRelativeChange]))
+	return nil
+}
+
+func (r *alertRecorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.alerts)
+}
+
+func priceOf(change float64) *collector.WindowPrice {
+	return &collector.WindowPrice{
+		Symbol1: "BTC", Symbol2: "USDT", Exchange: "binance",
+		OpenPrice: 100, ClosePrice: 100 + change,
+		AbsolutePriceChange: change, RelativePriceChange: change,
+	}
+}
+
+func TestStepAlertsPerDirection(t *testing.T) {
+	ctx, cancel := context.WithCancel(status.NewContext(context.Background(), status.NewTracker("btc")))
+	defer cancel()
+	rawConf := `{"type": "price_change", "name": "btc", "symbol1": "BTC", "symbol2": "USDT", "percentage": 1, "step": 2, "repeat_interval": "1h"}`
+	s, err := NewPriceChangeStrategy(ctx, []byte(rawConf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rises to 3.6% in steps, then falls as far, the magnitude of the fall counts for its steps
+	var prices []*collector.WindowPrice
+	for _, change := range []float64{1.5, 2.5, 3.6, -1.5, -3.6, -2} {
+		prices = append(prices, priceOf(change))
+	}
+	recorder := &alertRecorder{}
+	s.AddCollectors(&fakeCollector{prices: prices})
+	s.AddNotifiers(recorder)
+	s.Run()
+
+	want := []string{
+		"firing binance/BTC-USDT/down -1.5",
+		"firing binance/BTC-USDT/down -3.6",
+		"firing binance/BTC-USDT/up 1.5",
+		"firing binance/BTC-USDT/up 3.6",
+		"resolved binance/BTC-USDT/up -1.5",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for recorder.count() < len(want) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(recorder.alerts)
+	if !reflect.DeepEqual(recorder.alerts, want) {
+		t.Errorf("alerted %q, want %q", recorder.alerts, want)
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/azraeljack/crypto-monitor/collector"
+	"github.com/azraeljack/crypto-monitor/config"
 	"github.com/azraeljack/crypto-monitor/notifier"
-	log "github.com/sirupsen/logrus"
 	"sync"
 )
 
-type Builder func(ctx context.Context, rawConf json.RawMessage) Strategy
+type Builder func(ctx context.Context, rawConf json.RawMessage) (Strategy, error)
 
 type Strategy interface {
 	Run()
+	// Wait blocks until the strategy has stopped after its context is done, including
+	// delivering the notifications it already matched, or until ctx is done.
+	Wait(ctx context.Context) error
 	AddCollectors(collector ...collector.Collector)
 	AddNotifiers(notifier ...notifier.Notifier)
 }
@@ -27,24 +30,30 @@ func (r *Registry) Register(tpy string, builder Builder) {
 	r.strategies.Store(tpy, builder)
 }
 
-func (r *Registry) GetStrategy(ctx context.Context, rawConf json.RawMessage) Strategy {
-	type collectorTypeConf struct {
-		Type string `json:"type"`
-	}
-
-	typeConf := &collectorTypeConf{}
+func (r *Registry) GetStrategy(ctx context.Context, rawConf json.RawMessage) (Strategy, error) {
+	typeConf := &config.Component{}
 	if err := json.Unmarshal(rawConf, typeConf); err != nil {
-		log.Panic("load config fail", err)
+		return nil, config.Errors{{Err: err}}
 	}
 
 	builder, exist := r.strategies.Load(typeConf.Type)
 	if !exist {
-		return nil
+		return nil, config.Errors{{Path: "type", Err: config.UnknownType(typeConf.Type, r.Types())}}
 	}
 
 	return builder.(Builder)(ctx, rawConf)
 }
 
+// Types lists the registered strategy types.
+func (r *Registry) Types() []string {
+	var types []string
+	r.strategies.Range(func(key, _ any) bool {
+		types = append(types, key.(string))
+		return true
+	})
+	return types
+}
+
 func GetRegistry() *Registry {
 	return &registry
 }
@@ -0,0 +1,106 @@
+package templates
+
+import (
+	"github.com/azraeljack/crypto-monitor/collector"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDecimals bounds the precision of formatted prices.
+const maxDecimals = 8
+
+// Data is what alert templates are rendered with. Prices are formatted with the precision
+// the exchange quotes them with.
+type Data struct {
+	Time     string
+	Strategy string
+	// Severity is info, warning or critical, State firing or resolved
+	Severity string
+	State    string
+
+	Pair     string
+	Symbol1  string
+	Symbol2  string
+	Exchange string
+
+	// Direction is "up" or "down", Arrow "▲" or "▼"
+	Direction string
+	Arrow     string
+
+	Price       string
+	Open        string
+	High        string
+	Low         string
+	Absolute    string
+	Percentage  string
+	Volume      string
+	OrderNumber string
+
+	// Window is the time window of the change, the thresholds those of the strategy
+	Window              string
+	AbsoluteThreshold   float64
+	PercentageThreshold float64
+
+	// Duration is how long a resolved alert lasted
+	Duration string
+}
+
+// NewData fills the fields about the price, the others are left to the strategy.
+func NewData(price *collector.WindowPrice) *Data {
+	precision := decimals(price.OpenPrice, price.ClosePrice)
+	data := &Data{
+		Time:        time.Now().Format("2006-01-02 15:04:05"),
+		Pair:        price.SymbolPair(),
+		Symbol1:     price.Symbol1,
+		Symbol2:     price.Symbol2,
+		Exchange:    price.Exchange,
+		Direction:   "up",
+		Arrow:       "▲",
+		Price:       strconv.FormatFloat(price.ClosePrice, 'f', precision, 64),
+		Open:        strconv.FormatFloat(price.OpenPrice, 'f', precision, 64),
+		High:        strconv.FormatFloat(price.HighPrice, 'f', precision, 64),
+		Low:         strconv.FormatFloat(price.LowPrice, 'f', precision, 64),
+		Absolute:    strconv.FormatFloat(price.AbsolutePriceChange, 'f', precision, 64),
+		Percentage:  strconv.FormatFloat(price.RelativePriceChange, 'f', 2, 64),
+		Volume:      strconv.FormatFloat(price.Volume, 'f', -1, 64),
+		OrderNumber: strconv.FormatUint(price.OrderCount, 10),
+	}
+	if !price.Rising() {
+		data.Direction, data.Arrow = "down", "▼"
+	}
+	return data
+}
+
+// decimals returns the most decimals any of the values has, up to maxDecimals.
+func decimals(values ...float64) int {
+	most := 0
+	for _, value := range values {
+		formatted := strconv.FormatFloat(value, 'f', -1, 64)
+		if i := strings.IndexByte(formatted, '.'); i >= 0 && len(formatted)-i-1 > most {
+			most = len(formatted) - i - 1
+		}
+	}
+	if most > maxDecimals {
+		return maxDecimals
+	}
+	return most
+}
+
+// sample is rendered at load time to catch templates using unknown fields.
+func sample() *Data {
+	data := NewData(&collector.WindowPrice{
+		Symbol1:             "BTC",
+		Symbol2:             "USDT",
+		OpenPrice:           60000,
+		ClosePrice:          63000.5,
+		HighPrice:           63100,
+		LowPrice:            59900,
+		AbsolutePriceChange: 3000.5,
+		RelativePriceChange: 5,
+		Exchange:            "binance",
+	})
+	data.Strategy, data.Severity, data.State = "sample", "warning", "firing"
+	data.Window, data.Duration = "15m0s", "5m0s"
+	return data
+}
@@ -0,0 +1,50 @@
+package templates
+
+const (
+	LocaleZh = "zh"
+	LocaleEn = "en"
+)
+
+var locales = []string{LocaleEn, LocaleZh}
+
+var defaultFiring = map[string]string{
+	LocaleZh: `发现价格波动：
+- 时间：{{.Time}}
+- 交易对：{{.Symbol1}} - {{.Symbol2}}{{if .Exchange}} ({{.Exchange}}){{end}}
+- 当前价格：{{.Price}}
+- 波动幅度：{{.Arrow}} {{.Absolute}} ({{.Percentage}}%)
+- 时间窗口：{{.Window}}
+- 成交笔数：{{.OrderNumber}}
+`,
+	LocaleEn: `Price change detected:
+- Time: {{.Time}}
+- Pair: {{.Symbol1}} - {{.Symbol2}}{{if .Exchange}} ({{.Exchange}}){{end}}
+- Price: {{.Price}}
+- Change: {{.Arrow}} {{.Absolute}} ({{.Percentage}}%)
+- Window: {{.Window}}
+- Trades: {{.OrderNumber}}
+`,
+}
+
+var defaultResolved = map[string]string{
+	LocaleZh: `价格波动已恢复：
+- 时间：{{.Time}}
+- 交易对：{{.Symbol1}} - {{.Symbol2}}{{if .Exchange}} ({{.Exchange}}){{end}}
+- 当前价格：{{.Price}}
+- 波动幅度：{{.Arrow}} {{.Absolute}} ({{.Percentage}}%)
+- 持续时间：{{.Duration}}
+`,
+	LocaleEn: `Price change resolved:
+- Time: {{.Time}}
+- Pair: {{.Symbol1}} - {{.Symbol2}}{{if .Exchange}} ({{.Exchange}}){{end}}
+- Price: {{.Price}}
+- Change: {{.Arrow}} {{.Absolute}} ({{.Percentage}}%)
+- Lasted: {{.Duration}}
+`,
+}
+
+// fieldNames name the price, change and trades fields of alerts shown apart from the message.
+var fieldNames = map[string][3]string{
+	LocaleZh: {"当前价格", "波动幅度", "成交笔数"},
+	LocaleEn: {"Price", "Change", "Trades"},
+}
@@ -0,0 +1,95 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/azraeljack/crypto-monitor/config"
+	"os"
+	"text/template"
+)
+
+// Set holds the compiled templates of alerts firing and resolved.
+type Set struct {
+	locale   string
+	firing   *template.Template
+	resolved *template.Template
+}
+
+// Parse compiles the templates of conf, which may be nil for the defaults of locale, and
+// checks them by rendering sample data. The locale of conf overrides locale.
+func Parse(conf *config.MessageTemplate, locale string) (*Set, error) {
+	if conf == nil {
+		conf = &config.MessageTemplate{}
+	}
+
+	errs := config.Errors{}
+	if len(conf.Locale) > 0 {
+		locale = conf.Locale
+	}
+	if err := CheckLocale(locale); err != nil {
+		errs.Add("locale", err)
+		return nil, errs.Err()
+	}
+
+	set := &Set{
+		locale:   locale,
+		firing:   compile(&errs, "firing", conf.Firing, conf.FiringFile, defaultFiring[locale]),
+		resolved: compile(&errs, "resolved", conf.Resolved, conf.ResolvedFile, defaultResolved[locale]),
+	}
+	return set, errs.Err()
+}
+
+// compile parses the inline or file template of field, or def if neither is set.
+func compile(errs *config.Errors, field, inline, file, def string) *template.Template {
+	text := def
+	switch {
+	case len(inline) > 0 && len(file) > 0:
+		errs.Addf(field, "%s and %s_file are exclusive", field, field)
+		return nil
+	case len(file) > 0:
+		content, err := os.ReadFile(file)
+		if err != nil {
+			errs.Add(field+"_file", err)
+			return nil
+		}
+		field, text = field+"_file", string(content)
+	case len(inline) > 0:
+		text = inline
+	}
+
+	tmpl, err := template.New(field).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		errs.Addf(field, "invalid template: %v", err)
+		return nil
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample()); err != nil {
+		errs.Addf(field, "invalid template: %v", err)
+		return nil
+	}
+	return tmpl
+}
+
+// Render renders data with the resolved template if resolved, the firing one otherwise.
+func (s *Set) Render(data *Data, resolved bool) (string, error) {
+	tmpl := s.firing
+	if resolved {
+		tmpl = s.resolved
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("render %s template: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// Locale returns the locale of the set.
+func (s *Set) Locale() string {
+	return s.locale
+}
+
+// FieldNames returns the names of the price, change and trades fields in the locale of the set.
+func (s *Set) FieldNames() (price, change, trades string) {
+	names := fieldNames[s.locale]
+	return names[0], names[1], names[2]
+}
@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+)
+
+// Texts are the messages of the monitor itself in a locale, formatted with the arguments
+// noted along with them.
+type Texts struct {
+	Started string
+	Stopped string
+
+	// GroupFiring and GroupResolved head a group of %d alerts, GroupLine lists one of them by
+	// its headline %s and price %v
+	GroupFiring   string
+	GroupResolved string
+	GroupLine     string
+
+	// QuietHours and Silenced tell when alerts were held back, Summary counts them after
+	// either as %s, %d and SummaryLine counts those of a label as %s, %d
+	QuietHours  string
+	Silenced    string
+	Summary     string
+	SummaryLine string
+
+	// Acknowledge links %s to acknowledge an alert, Escalated heads an alert unacknowledged
+	// for %v and Acknowledged confirms the acknowledgement of alert %s. AcknowledgePage asks
+	// whether to acknowledge an alert, with a button labeled AcknowledgeButton
+	Acknowledge       string
+	Escalated         string
+	Acknowledged      string
+	AcknowledgePage   string
+	AcknowledgeButton string
+}
+
+var texts = map[string]*Texts{
+	LocaleZh: {
+		Started:           "监控程序已启动",
+		Stopped:           "监控程序已停止",
+		GroupFiring:       "%d 个交易对同时触发告警：\n",
+		GroupResolved:     "%d 个交易对的告警已恢复：\n",
+		GroupLine:         "- %s，现价 %v\n",
+		QuietHours:        "安静时段内",
+		Silenced:          "静默期间",
+		Summary:           "%s抑制了 %d 条通知：\n",
+		SummaryLine:       "- %s：%d 条\n",
+		Acknowledge:       "确认告警：%s",
+		Escalated:         "【告警升级】以下告警 %v 内无人确认：",
+		Acknowledged:      "已确认告警，停止升级：\n%s\n",
+		AcknowledgePage:   "确认以下告警并停止升级？",
+		AcknowledgeButton: "确认告警",
+	},
+	LocaleEn: {
+		Started:           "Monitor started",
+		Stopped:           "Monitor stopped",
+		GroupFiring:       "%d pairs alerting at once:\n",
+		GroupResolved:     "%d pairs resolved:\n",
+		GroupLine:         "- %s, price %v\n",
+		QuietHours:        "During quiet hours",
+		Silenced:          "While silenced",
+		Summary:           "%s, %d notifications were held back:\n",
+		SummaryLine:       "- %s: %d\n",
+		Acknowledge:       "Acknowledge: %s",
+		Escalated:         "[ESCALATED] Nobody acknowledged this alert within %v:",
+		Acknowledged:      "Alert acknowledged, escalation stopped:\n%s\n",
+		AcknowledgePage:   "Acknowledge this alert and stop its escalation?",
+		AcknowledgeButton: "Acknowledge",
+	},
+}
+
+// TextsOf returns the texts of a locale, the Chinese ones if it's unknown.
+func TextsOf(locale string) *Texts {
+	if t, exist := texts[locale]; exist {
+		return t
+	}
+	return texts[LocaleZh]
+}
+
+// CheckLocale returns an error if locale isn't supported.
+func CheckLocale(locale string) error {
+	if _, exist := texts[locale]; !exist {
+		return fmt.Errorf("unknown locale %q, expecting one of %v", locale, locales)
+	}
+	return nil
+}
+
+type localeKey struct{}
+
+// NewContext returns a context carrying the default locale of the components built with it.
+func NewContext(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale of ctx, Chinese if it carries none.
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && len(locale) > 0 {
+		return locale
+	}
+	return LocaleZh
+}